@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	telebot "gopkg.in/telebot.v3"
+)
+
+func (watcher *Watcher) setNextEnforce(next time.Time) {
+	watcher.mutex.Lock()
+	watcher.nextEnforce = next
+	watcher.mutex.Unlock()
+}
+
+func (watcher *Watcher) getNextEnforce() time.Time {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	return watcher.nextEnforce
+}
+
+func buildInfo() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	parts := []string{info.GoVersion}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision", "vcs.time":
+			parts = append(parts, setting.Value)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (watcher *Watcher) handleAbout(context telebot.Context) error {
+	next := "not scheduled"
+	if moment := watcher.getNextEnforce(); !moment.IsZero() {
+		next = moment.Format(time.RFC1123)
+	}
+
+	text := fmt.Sprintf(
+		"telekick %s removes members who have been silent for too long.\n\n"+
+			"Build: %s\n"+
+			"Uptime: %v\n"+
+			"Inactivity threshold: %s\n"+
+			"Next enforcement run: %s\n\n"+
+			"Send /when to see everyone's last activity "+
+			"and /stats for the community summary.",
+		version,
+		buildInfo(),
+		time.Since(watcher.started).Round(time.Second),
+		humanDuration(watcher.policy().Duration, watcher.chatLanguage()),
+		next,
+	)
+
+	if watcher.aboutURL != "" {
+		text += "\nHistory and rules: " + watcher.aboutURL
+	}
+
+	return context.Reply(text)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+)
+
+const (
+	auditKick        = actionKick
+	auditMute        = actionMute
+	auditPardon      = "pardon"
+	auditJoinApprove = "join_approve"
+	auditJoinDecline = "join_decline"
+	auditRename      = "rename"
+	auditWarn        = "warn"
+	auditSaved       = "saved"
+	auditError       = "error"
+	auditRemove      = "remove"
+	auditRejoin      = "rejoin"
+	auditSettings    = "settings"
+	auditCancel      = "cancel"
+	auditVouch       = "vouch"
+)
+
+type AuditRecord struct {
+	UserID   int64  `bson:"user_id"`
+	Username string `bson:"username,omitempty"`
+	Action   string `bson:"action"`
+	Actor    int64  `bson:"actor,omitempty"`
+	Active   bool   `bson:"active"`
+	Cycle    string `bson:"cycle,omitempty"`
+	Details  string `bson:"details,omitempty"`
+	Time     int64  `bson:"time"`
+}
+
+func (watcher *Watcher) record(record AuditRecord) error {
+	if record.Time == 0 {
+		record.Time = time.Now().Unix()
+	}
+
+	err := watcher.audit.Insert(record)
+	if err != nil {
+		return karma.Format(err, "insert audit record: %s", record.Action)
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) findEnforced(username string) (*AuditRecord, error) {
+	var record AuditRecord
+	err := watcher.audit.Find(bson.M{
+		"action":   bson.M{"$in": []string{auditKick, auditMute}},
+		"username": username,
+	}).Sort("-time").One(&record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const (
+	logDefault = 10
+	logMax     = 50
+)
+
+func formatAuditRecord(record AuditRecord, language string) string {
+	parts := []string{
+		time.Unix(record.Time, 0).Format("2006-01-02 15:04"),
+		auditActionName(record.Action, language),
+	}
+
+	if record.Username != "" {
+		parts = append(parts, "@"+record.Username)
+	} else if record.UserID != 0 {
+		parts = append(parts, strconv.FormatInt(record.UserID, 10))
+	}
+
+	if record.Actor != 0 {
+		parts = append(parts, fmt.Sprintf("%s %v", auditActorPrefixes[language], record.Actor))
+	}
+
+	if record.Details != "" {
+		parts = append(parts, record.Details)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (watcher *Watcher) handleLog(context telebot.Context) error {
+	limit := logDefault
+	if args := context.Args(); len(args) > 0 {
+		value, err := strconv.Atoi(args[0])
+		if err != nil || value <= 0 {
+			return context.Reply("Usage: /log [n]")
+		}
+
+		limit = value
+	}
+
+	if limit > logMax {
+		limit = logMax
+	}
+
+	var records []AuditRecord
+	err := watcher.audit.Find(bson.M{}).Sort("-time").Limit(limit).All(&records)
+	if err != nil {
+		return karma.Format(err, "find audit records")
+	}
+
+	if len(records) == 0 {
+		return context.Reply(emptyLogTexts[watcher.chatLanguage()])
+	}
+
+	lines := []string{}
+	for i := len(records) - 1; i >= 0; i-- {
+		lines = append(lines, formatAuditRecord(records[i], watcher.chatLanguage()))
+	}
+
+	return context.Reply(strings.Join(lines, "\n"))
+}
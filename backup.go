@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/klauspost/compress/zstd"
+	"github.com/minio/minio-go/v7"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+)
+
+const backupSuffix = ".bson.zst"
+
+type Backup struct {
+	Time        int64               `bson:"time"`
+	Collections map[string][]bson.M `bson:"collections"`
+}
+
+type BackupEntry struct {
+	Collection string `bson:"collection"`
+	Document   bson.M `bson:"document,omitempty"`
+}
+
+type Backuper struct {
+	client    *minio.Client
+	bucket    string
+	prefix    string
+	interval  time.Duration
+	retention time.Duration
+}
+
+func (watcher *Watcher) collections() map[string]*mgo.Collection {
+	return map[string]*mgo.Collection{
+		watcher.store.Name:         watcher.store,
+		watcher.audit.Name:         watcher.audit,
+		watcher.history.Name:       watcher.history,
+		watcher.settingsStore.Name: watcher.settingsStore,
+		watcher.snapshots.Name:     watcher.snapshots,
+		watcher.cycles.Name:        watcher.cycles,
+	}
+}
+
+func (watcher *Watcher) Backup() {
+	for {
+		name, err := watcher.backup()
+		if err != nil {
+			log.Errorf(err, "backup")
+		} else {
+			log.Infof(nil, "backup uploaded: %s", name)
+		}
+
+		err = watcher.pruneBackups()
+		if err != nil {
+			log.Errorf(err, "prune backups")
+		}
+
+		time.Sleep(watcher.backuper.interval)
+	}
+}
+
+func (watcher *Watcher) backup() (string, error) {
+	name := watcher.backuper.prefix +
+		time.Now().UTC().Format("20060102T150405Z") +
+		backupSuffix
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(watcher.dump(writer))
+	}()
+
+	_, err := watcher.backuper.client.PutObject(
+		context.Background(),
+		watcher.backuper.bucket,
+		name,
+		reader,
+		-1,
+		minio.PutObjectOptions{ContentType: "application/zstd"},
+	)
+
+	reader.CloseWithError(io.ErrClosedPipe)
+
+	if err != nil {
+		return "", karma.Format(err, "upload backup: %s", name)
+	}
+
+	return name, nil
+}
+
+func (watcher *Watcher) dump(writer io.Writer) error {
+	encoder, err := zstd.NewWriter(writer)
+	if err != nil {
+		return karma.Format(err, "create zstd encoder")
+	}
+
+	for name, collection := range watcher.collections() {
+		err = writeBackupEntry(encoder, BackupEntry{Collection: name})
+		if err != nil {
+			return err
+		}
+
+		iter := collection.Find(nil).Batch(enforceBatch).Iter()
+
+		for {
+			var document bson.M
+			if !iter.Next(&document) {
+				break
+			}
+
+			err = writeBackupEntry(
+				encoder,
+				BackupEntry{Collection: name, Document: document},
+			)
+			if err != nil {
+				iter.Close()
+				return err
+			}
+		}
+
+		err = iter.Close()
+		if err != nil {
+			return karma.Format(err, "dump collection: %s", name)
+		}
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return karma.Format(err, "compress backup")
+	}
+
+	return nil
+}
+
+func writeBackupEntry(writer io.Writer, entry BackupEntry) error {
+	data, err := bson.Marshal(entry)
+	if err != nil {
+		return karma.Format(err, "marshal backup entry: %s", entry.Collection)
+	}
+
+	_, err = writer.Write(data)
+	if err != nil {
+		return karma.Format(err, "compress backup")
+	}
+
+	return nil
+}
+
+func readBackupDocument(reader io.Reader) ([]byte, error) {
+	var size [4]byte
+
+	_, err := io.ReadFull(reader, size[:])
+	if err != nil {
+		return nil, err
+	}
+
+	length := binary.LittleEndian.Uint32(size[:])
+	if length < 5 {
+		return nil, karma.Describe("size", length).Reason("invalid document")
+	}
+
+	data := make([]byte, length)
+	copy(data, size[:])
+
+	_, err = io.ReadFull(reader, data[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (watcher *Watcher) pruneBackups() error {
+	if watcher.backuper.retention <= 0 {
+		return nil
+	}
+
+	objects := watcher.backuper.client.ListObjects(
+		context.Background(),
+		watcher.backuper.bucket,
+		minio.ListObjectsOptions{
+			Prefix:    watcher.backuper.prefix,
+			Recursive: true,
+		},
+	)
+
+	for object := range objects {
+		if object.Err != nil {
+			return karma.Format(object.Err, "list backups")
+		}
+
+		if time.Since(object.LastModified) < watcher.backuper.retention {
+			continue
+		}
+
+		log.Infof(nil, "remove expired backup: %s", object.Key)
+
+		err := watcher.backuper.client.RemoveObject(
+			context.Background(),
+			watcher.backuper.bucket,
+			object.Key,
+			minio.RemoveObjectOptions{},
+		)
+		if err != nil {
+			return karma.Format(err, "remove backup: %s", object.Key)
+		}
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) restore(name string) error {
+	object, err := watcher.backuper.client.GetObject(
+		context.Background(),
+		watcher.backuper.bucket,
+		name,
+		minio.GetObjectOptions{},
+	)
+	if err != nil {
+		return karma.Format(err, "download backup: %s", name)
+	}
+
+	defer object.Close()
+
+	decoder, err := zstd.NewReader(object)
+	if err != nil {
+		return karma.Format(err, "create zstd decoder")
+	}
+
+	defer decoder.Close()
+
+	data, err := readBackupDocument(decoder)
+	if err != nil {
+		return karma.Format(err, "read backup: %s", name)
+	}
+
+	var entry BackupEntry
+	err = bson.Unmarshal(data, &entry)
+	if err != nil {
+		return karma.Format(err, "unmarshal backup: %s", name)
+	}
+
+	if entry.Collection == "" {
+		return watcher.restoreLegacy(data)
+	}
+
+	collections := watcher.collections()
+	restored := map[string]int{}
+
+	for {
+		collection, ok := collections[entry.Collection]
+		switch {
+		case !ok:
+			if _, seen := restored[entry.Collection]; !seen {
+				log.Warningf(
+					nil,
+					"skip unknown collection in backup: %s",
+					entry.Collection,
+				)
+				restored[entry.Collection] = 0
+			}
+
+		case len(entry.Document) == 0:
+			_, err = collection.RemoveAll(nil)
+			if err != nil {
+				return karma.Format(err, "clear collection: %s", entry.Collection)
+			}
+
+			restored[entry.Collection] = 0
+
+		default:
+			err = collection.Insert(entry.Document)
+			if err != nil {
+				return karma.Format(
+					err,
+					"restore document into: %s",
+					entry.Collection,
+				)
+			}
+
+			restored[entry.Collection]++
+		}
+
+		data, err = readBackupDocument(decoder)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return karma.Format(err, "read backup: %s", name)
+		}
+
+		entry = BackupEntry{}
+		err = bson.Unmarshal(data, &entry)
+		if err != nil {
+			return karma.Format(err, "unmarshal backup: %s", name)
+		}
+	}
+
+	for name, count := range restored {
+		if _, ok := collections[name]; ok {
+			log.Infof(nil, "restored %d documents into %s", count, name)
+		}
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) restoreLegacy(data []byte) error {
+	var backup Backup
+	err := bson.Unmarshal(data, &backup)
+	if err != nil {
+		return karma.Format(err, "unmarshal backup")
+	}
+
+	collections := watcher.collections()
+
+	for name, documents := range backup.Collections {
+		collection, ok := collections[name]
+		if !ok {
+			log.Warningf(nil, "skip unknown collection in backup: %s", name)
+			continue
+		}
+
+		_, err = collection.RemoveAll(nil)
+		if err != nil {
+			return karma.Format(err, "clear collection: %s", name)
+		}
+
+		for _, document := range documents {
+			err = collection.Insert(document)
+			if err != nil {
+				return karma.Format(err, "restore document into: %s", name)
+			}
+		}
+
+		log.Infof(nil, "restored %d documents into %s", len(documents), name)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const (
+	banUserIsAdmin     = "user is admin"
+	banNotEnoughRights = "not enough rights"
+	banUserNotFound    = "user not found"
+	banChatNotFound    = "chat not found"
+	banUnknown         = "unknown"
+)
+
+const (
+	unbannableRetry  = "retry"
+	unbannableSkip   = "skip"
+	unbannableNotify = "notify"
+)
+
+var unbannableActions = []string{unbannableRetry, unbannableSkip, unbannableNotify}
+
+type BanError struct {
+	Reason string
+	UserID int64
+	Err    error
+}
+
+func (err *BanError) Error() string {
+	return fmt.Sprintf("ban %v: %s: %s", loggedUser(err.UserID), err.Reason, err.Err)
+}
+
+func (err *BanError) Unwrap() error {
+	return err.Err
+}
+
+func (err *BanError) Fatal() bool {
+	return err.Reason == banNotEnoughRights || err.Reason == banChatNotFound
+}
+
+func (watcher *Watcher) unbannableAction() string {
+	if action := watcher.getSettings().Unbannable; action != "" {
+		return action
+	}
+
+	return unbannableRetry
+}
+
+func (watcher *Watcher) handleUnbannable(
+	context *karma.Context,
+	cycle string,
+	user User,
+	banErr *BanError,
+) {
+	action := watcher.unbannableAction()
+	if action == unbannableRetry {
+		return
+	}
+
+	if banErr.Fatal() {
+		if action == unbannableNotify &&
+			time.Since(watcher.unbannableNotified) > day {
+			watcher.unbannableNotified = time.Now()
+
+			err := watcher.notifyAdmins(
+				"I can't remove inactive members from the chat: " +
+					banErr.Reason + ". Please check my admin rights." +
+					"\nCycle: " + cycle,
+			)
+			if err != nil {
+				log.Error(context.Reason(err))
+			}
+		}
+
+		return
+	}
+
+	if banErr.Reason != banUserIsAdmin {
+		return
+	}
+
+	err := watcher.store.Update(
+		bson.M{"user_id": user.UserID},
+		bson.M{"$set": bson.M{"unbannable": true}},
+	)
+	if err != nil {
+		log.Errorf(context.Reason(err), "mark %v as unbannable", loggedUser(user.UserID))
+	}
+
+	if action == unbannableNotify {
+		err := watcher.notifyAdmins(fmt.Sprintf(
+			"%s (%v) is inactive but can't be removed because they are "+
+				"a chat admin. I will skip them until they post again.\n"+
+				"Cycle: %s",
+			displayName(user.Username, user.FirstName, user.LastName),
+			user.UserID,
+			cycle,
+		))
+		if err != nil {
+			log.Error(context.Reason(err))
+		}
+	}
+}
+
+func classifyBanError(user int64, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	reason := banUnknown
+
+	switch err {
+	case telebot.ErrUserIsAdmin, telebot.ErrCantRemoveOwner:
+		reason = banUserIsAdmin
+	case telebot.ErrNoRightsToRestrict:
+		reason = banNotEnoughRights
+	case telebot.ErrChatNotFound:
+		reason = banChatNotFound
+	default:
+		message := strings.ToLower(err.Error())
+		switch {
+		case strings.Contains(message, "administrator"),
+			strings.Contains(message, "chat owner"):
+			reason = banUserIsAdmin
+		case strings.Contains(message, "not enough rights"),
+			strings.Contains(message, "chat_admin_required"):
+			reason = banNotEnoughRights
+		case strings.Contains(message, "user not found"),
+			strings.Contains(message, "participant_id_invalid"):
+			reason = banUserNotFound
+		case strings.Contains(message, "chat not found"):
+			reason = banChatNotFound
+		}
+	}
+
+	return &BanError{Reason: reason, UserID: user, Err: err}
+}
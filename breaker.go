@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+)
+
+var metricBreakerTrips = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "telekick_circuit_breaker_trips_total",
+	Help: "Number of times enforcement was stopped by the circuit breaker.",
+})
+
+type CircuitBreaker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	failures  []time.Time
+	openUntil time.Time
+	mutex     sync.Mutex
+}
+
+func NewCircuitBreaker(
+	threshold int,
+	window time.Duration,
+	cooldown time.Duration,
+) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+	}
+}
+
+func (breaker *CircuitBreaker) Open() bool {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	return time.Now().Before(breaker.openUntil)
+}
+
+func (breaker *CircuitBreaker) OpenUntil() time.Time {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	return breaker.openUntil
+}
+
+func (breaker *CircuitBreaker) Fail() bool {
+	if breaker.threshold <= 0 {
+		return false
+	}
+
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	now := time.Now()
+
+	recent := breaker.failures[:0]
+	for _, failure := range breaker.failures {
+		if now.Sub(failure) < breaker.window {
+			recent = append(recent, failure)
+		}
+	}
+
+	breaker.failures = append(recent, now)
+
+	if len(breaker.failures) < breaker.threshold || now.Before(breaker.openUntil) {
+		return false
+	}
+
+	breaker.failures = nil
+	breaker.openUntil = now.Add(breaker.cooldown)
+
+	return true
+}
+
+func (watcher *Watcher) tripBreaker(context *karma.Context, cycle string) {
+	until := watcher.breaker.OpenUntil()
+
+	log.Warningf(
+		context.Format(nil, "too many Bot API failures"),
+		"enforcement is stopped until %v",
+		until,
+	)
+
+	metricBreakerTrips.Inc()
+
+	err := watcher.notifyAdmins(fmt.Sprintf(
+		"Telegram API keeps failing, so I stopped removing inactive members "+
+			"until %s. Activity is still being recorded.\n"+
+			"Cycle: %s",
+		until.Format(time.RFC1123),
+		cycle,
+	))
+	if err != nil {
+		log.Error(context.Reason(err))
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTrips(t *testing.T) {
+	breaker := NewCircuitBreaker(3, time.Minute, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if breaker.Fail() {
+			t.Fatalf("tripped after %d failures", i+1)
+		}
+	}
+
+	if breaker.Open() {
+		t.Fatalf("open before threshold")
+	}
+
+	if !breaker.Fail() {
+		t.Fatalf("not tripped at threshold")
+	}
+
+	if !breaker.Open() {
+		t.Fatalf("not open after tripping")
+	}
+
+	until := breaker.OpenUntil()
+	if until.Before(time.Now().Add(59*time.Minute)) || until.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("unexpected cooldown: %v", until)
+	}
+
+	for i := 0; i < 3; i++ {
+		if breaker.Fail() {
+			t.Fatalf("tripped again while open")
+		}
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	breaker := NewCircuitBreaker(2, time.Minute, time.Hour)
+	breaker.failures = []time.Time{time.Now().Add(-2 * time.Minute)}
+
+	if breaker.Fail() {
+		t.Fatalf("tripped by a failure outside of the window")
+	}
+
+	if !breaker.Fail() {
+		t.Fatalf("not tripped by failures inside of the window")
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker := NewCircuitBreaker(0, time.Minute, time.Hour)
+
+	for i := 0; i < 100; i++ {
+		if breaker.Fail() {
+			t.Fatalf("disabled breaker tripped")
+		}
+	}
+
+	if breaker.Open() {
+		t.Fatalf("disabled breaker is open")
+	}
+}
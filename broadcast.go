@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const broadcastInterval = time.Second / 20
+
+type Delivery struct {
+	Total       int     `json:"total"`
+	Sent        []int64 `json:"sent"`
+	Unreachable []int64 `json:"unreachable"`
+	Failed      []int64 `json:"failed"`
+}
+
+func (watcher *Watcher) atRiskQuery() bson.M {
+	policy := watcher.policy()
+
+	window := policy.WarnBefore
+	if window <= 0 {
+		window = week
+	}
+
+	return bson.M{
+		"last_message": bson.M{
+			"$lt": time.Now().Add(window - policy.Duration).Unix(),
+		},
+	}
+}
+
+func (watcher *Watcher) broadcast(path string, onlyAtRisk bool) (*Delivery, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, karma.Format(err, "read message: %s", path)
+	}
+
+	text := strings.TrimSpace(string(contents))
+	if text == "" {
+		return nil, karma.Format(nil, "message is empty: %s", path)
+	}
+
+	query := bson.M{}
+	if onlyAtRisk {
+		query = watcher.atRiskQuery()
+	}
+
+	var users []User
+	err = watcher.store.Find(query).Sort("user_id").All(&users)
+	if err != nil {
+		return nil, karma.Format(err, "find users")
+	}
+
+	queue := NewSendQueue(watcher.bot, broadcastInterval)
+
+	delivery := &Delivery{Total: len(users)}
+	for _, user := range users {
+		_, err := queue.Send(&telebot.User{ID: user.UserID}, text)
+		if err != nil {
+			if isForbidden(err) {
+				watcher.setUnreachable(user.UserID, true)
+				delivery.Unreachable = append(delivery.Unreachable, user.UserID)
+				continue
+			}
+
+			log.Errorf(err, "broadcast to %v", loggedUser(user.UserID))
+			delivery.Failed = append(delivery.Failed, user.UserID)
+			continue
+		}
+
+		watcher.setUnreachable(user.UserID, false)
+		delivery.Sent = append(delivery.Sent, user.UserID)
+	}
+
+	log.Infof(
+		nil,
+		"broadcast delivered: %d/%d, unreachable: %d, failed: %d",
+		len(delivery.Sent), delivery.Total,
+		len(delivery.Unreachable), len(delivery.Failed),
+	)
+
+	return delivery, nil
+}
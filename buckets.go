@@ -0,0 +1,16 @@
+package main
+
+import "time"
+
+const week = 7 * 24 * time.Hour
+
+func idleBucket(idle time.Duration) string {
+	switch {
+	case idle < week:
+		return "<1w"
+	case idle <= 4*week:
+		return "1–4w"
+	default:
+		return ">4w"
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import "time"
+
+func clockJump(previous, now time.Time) time.Duration {
+	wall := now.Round(0).Sub(previous.Round(0))
+	monotonic := now.Sub(previous)
+
+	jump := wall - monotonic
+	if jump < 0 {
+		return -jump
+	}
+
+	return jump
+}
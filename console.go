@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/globalsign/mgo"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const maxMessageLength = 4096
+
+const consoleUsage = `Console commands:
+stats
+run-once [dry-run]
+set [chat <id>] <preset|language|unbannable|duration> <value>
+pardon <@user|id> [in chat <id>]`
+
+func (watcher *Watcher) consoleMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(context telebot.Context) error {
+		message := context.Message()
+		if watcher.owner == 0 ||
+			message == nil ||
+			!message.Private() ||
+			context.Sender() == nil ||
+			context.Sender().ID != watcher.owner ||
+			message.Text == "" ||
+			strings.HasPrefix(message.Text, "/") {
+			return next(context)
+		}
+
+		return watcher.handleConsole(context, strings.Fields(message.Text))
+	}
+}
+
+func (watcher *Watcher) consoleChat(fields []string) ([]string, bool) {
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] != "chat" {
+			continue
+		}
+
+		chat, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil || chat != watcher.getChat().ID {
+			return nil, false
+		}
+
+		start := i
+		if start > 0 && fields[start-1] == "in" {
+			start--
+		}
+
+		return append(append([]string{}, fields[:start]...), fields[i+2:]...), true
+	}
+
+	return fields, true
+}
+
+func (watcher *Watcher) handleConsole(context telebot.Context, fields []string) error {
+	if len(fields) > 0 {
+		log.Infof(
+			nil,
+			"console command from owner: %s %s",
+			fields[0], loggedName(strings.Join(fields[1:], " ")),
+		)
+	}
+
+	fields, ok := watcher.consoleChat(fields)
+	if !ok {
+		return context.Send(
+			"This instance only manages chat " +
+				strconv.FormatInt(watcher.getChat().ID, 10) + ".",
+		)
+	}
+
+	if len(fields) == 0 {
+		return context.Send(consoleUsage)
+	}
+
+	switch command, args := fields[0], fields[1:]; command {
+	case "stats":
+		summary, err := watcher.summary()
+		if err != nil {
+			return err
+		}
+
+		return context.Send(summary)
+
+	case "run-once":
+		dryRun := len(args) == 1 && args[0] == "dry-run"
+
+		summary, err := watcher.enforce(dryRun)
+		if err != nil {
+			return err
+		}
+
+		if summary.Skipped == skippedRunning {
+			return context.Send("Cycle already running.")
+		}
+
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if len(data) <= maxMessageLength {
+			return context.Send(string(data))
+		}
+
+		return context.Send(&telebot.Document{
+			File:     telebot.FromReader(bytes.NewReader(data)),
+			FileName: "run-once-" + summary.Cycle + ".json",
+			Caption:  "Cycle: " + summary.Cycle,
+		})
+
+	case "set":
+		if watcher.standby {
+			return context.Send(standbyNotice)
+		}
+
+		if len(args) != 2 {
+			return context.Send(consoleUsage)
+		}
+
+		update := settingsUpdate(args[0], args[1])
+		if update == nil {
+			return context.Send("Invalid value for " + args[0] + ".")
+		}
+
+		err := watcher.updateSettings(watcher.owner, update)
+		if err != nil {
+			return err
+		}
+
+		return context.Send("Settings applied.")
+
+	case "pardon":
+		if watcher.standby {
+			return context.Send(standbyNotice)
+		}
+
+		if len(args) != 1 {
+			return context.Send(consoleUsage)
+		}
+
+		target, err := watcher.findPardonTarget(args[0])
+		if err != nil {
+			if err == mgo.ErrNotFound {
+				return context.Send("No kicked or muted user " + args[0] + " found.")
+			}
+
+			return err
+		}
+
+		err = watcher.pardon(target.UserID, target.Username, watcher.owner)
+		if err != nil {
+			return err
+		}
+
+		return context.Send("Pardoned " + args[0] + ".")
+	}
+
+	return context.Send(consoleUsage)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	telebot "gopkg.in/telebot.v3"
+)
+
+type Cooldown struct {
+	mutex    sync.Mutex
+	duration time.Duration
+	last     map[int64]time.Time
+}
+
+func NewCooldown(duration time.Duration) *Cooldown {
+	return &Cooldown{
+		duration: duration,
+		last:     map[int64]time.Time{},
+	}
+}
+
+func (cooldown *Cooldown) Allow(user int64) (time.Duration, bool) {
+	cooldown.mutex.Lock()
+	defer cooldown.mutex.Unlock()
+
+	now := time.Now()
+
+	for id, last := range cooldown.last {
+		if now.Sub(last) >= cooldown.duration {
+			delete(cooldown.last, id)
+		}
+	}
+
+	if last, ok := cooldown.last[user]; ok {
+		return cooldown.duration - now.Sub(last), false
+	}
+
+	cooldown.last[user] = now
+
+	return 0, true
+}
+
+func (cooldown *Cooldown) Middleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(context telebot.Context) error {
+		if cooldown.duration <= 0 || context.Sender() == nil {
+			return next(context)
+		}
+
+		wait, ok := cooldown.Allow(context.Sender().ID)
+		if !ok {
+			return context.Reply(fmt.Sprintf(
+				"Please try again in %d seconds.",
+				int(math.Ceil(wait.Seconds())),
+			))
+		}
+
+		return next(context)
+	}
+}
+
+type RenderCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedRender
+}
+
+type cachedRender struct {
+	text string
+	at   time.Time
+}
+
+func NewRenderCache(ttl time.Duration) *RenderCache {
+	return &RenderCache{
+		ttl:     ttl,
+		entries: map[string]cachedRender{},
+	}
+}
+
+func (cache *RenderCache) Get(
+	key string,
+	render func() (string, error),
+) (string, error) {
+	if cache.ttl <= 0 {
+		return render()
+	}
+
+	cache.mutex.Lock()
+	entry, ok := cache.entries[key]
+	cache.mutex.Unlock()
+
+	if ok && time.Since(entry.at) < cache.ttl {
+		return entry.text, nil
+	}
+
+	text, err := render()
+	if err != nil {
+		return "", err
+	}
+
+	cache.mutex.Lock()
+	cache.entries[key] = cachedRender{text: text, at: time.Now()}
+	cache.mutex.Unlock()
+
+	return text, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+)
+
+const cycleResumeMax = 2 * enforceInterval
+
+type Cycle struct {
+	ID       string `bson:"cycle"`
+	Time     int64  `bson:"time"`
+	Finished int64  `bson:"finished"`
+}
+
+func (watcher *Watcher) startCycle() (string, bool, error) {
+	now := time.Now()
+	cutoff := now.Add(-cycleResumeMax).Unix()
+
+	_, err := watcher.cycles.UpdateAll(
+		bson.M{"finished": 0, "time": bson.M{"$lt": cutoff}},
+		bson.M{"$set": bson.M{"finished": now.Unix()}},
+	)
+	if err != nil {
+		return "", false, karma.Format(err, "abandon stale cycles")
+	}
+
+	var cycle Cycle
+	err = watcher.cycles.Find(
+		bson.M{"finished": 0, "time": bson.M{"$gte": cutoff}},
+	).Sort("-time").One(&cycle)
+	if err == nil {
+		return cycle.ID, true, nil
+	}
+
+	if err != mgo.ErrNotFound {
+		return "", false, karma.Format(err, "find unfinished cycle")
+	}
+
+	cycle = Cycle{
+		ID:   bson.NewObjectId().Hex(),
+		Time: now.Unix(),
+	}
+
+	err = watcher.cycles.Insert(cycle)
+	if err != nil {
+		return "", false, karma.Format(err, "insert cycle")
+	}
+
+	return cycle.ID, false, nil
+}
+
+func (watcher *Watcher) finishCycle(cycle string) error {
+	err := watcher.cycles.Update(
+		bson.M{"cycle": cycle},
+		bson.M{"$set": bson.M{"finished": time.Now().Unix()}},
+	)
+	if err != nil {
+		return karma.Format(err, "finish cycle: %s", cycle)
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) ensureCycleIndex() error {
+	err := watcher.audit.EnsureIndex(mgo.Index{
+		Key: []string{"cycle", "user_id"},
+	})
+	if err != nil {
+		return karma.Format(err, "ensure audit cycle index")
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) processed(cycle string, user int64) (bool, error) {
+	count, err := watcher.audit.Find(bson.M{
+		"cycle":   cycle,
+		"user_id": user,
+		"action":  bson.M{"$in": []string{auditKick, auditMute}},
+	}).Count()
+	if err != nil {
+		return false, karma.Format(err, "find processed user: %v", loggedUser(user))
+	}
+
+	return count > 0, nil
+}
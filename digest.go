@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+)
+
+func (watcher *Watcher) Digest() {
+	for {
+		time.Sleep(watcher.digestInterval)
+
+		err := watcher.sendDigest(time.Now().Add(-watcher.digestInterval))
+		if err != nil {
+			log.Errorf(err, "send digest")
+		}
+	}
+}
+
+func (watcher *Watcher) countActions(action string, since time.Time) (int, error) {
+	count, err := watcher.audit.Find(bson.M{
+		"action": action,
+		"time":   bson.M{"$gte": since.Unix()},
+	}).Count()
+	if err != nil {
+		return 0, karma.Format(err, "count %s records", action)
+	}
+
+	return count, nil
+}
+
+func (watcher *Watcher) digest(since time.Time) (string, error) {
+	warned, err := watcher.countActions(auditWarn, since)
+	if err != nil {
+		return "", err
+	}
+
+	saved, err := watcher.countActions(auditSaved, since)
+	if err != nil {
+		return "", err
+	}
+
+	kicked, err := watcher.countActions(auditKick, since)
+	if err != nil {
+		return "", err
+	}
+
+	effectiveness := "n/a"
+	if warned > 0 {
+		effectiveness = fmt.Sprintf("%.0f%%", float64(saved)/float64(warned)*100)
+	}
+
+	return fmt.Sprintf(
+		"Digest since %s\n\n"+
+			"Warned: %d\n"+
+			"Saved by warnings: %d\n"+
+			"Warning effectiveness: %s\n"+
+			"Kicked: %d",
+		since.Format(time.RFC1123),
+		warned,
+		saved,
+		effectiveness,
+		kicked,
+	), nil
+}
+
+func (watcher *Watcher) sendDigest(since time.Time) error {
+	text, err := watcher.digest(since)
+	if err != nil {
+		return err
+	}
+
+	return watcher.notifyAdmins(text)
+}
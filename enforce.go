@@ -0,0 +1,295 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+)
+
+const (
+	enforceInterval = time.Hour
+	enforceBatch    = 100
+
+	skippedRunning = "cycle already running"
+	skippedStopped = "stopped"
+)
+
+type Summary struct {
+	Cycle   string  `json:"cycle"`
+	Time    int64   `json:"time"`
+	DryRun  bool    `json:"dry_run"`
+	Skipped string  `json:"skipped,omitempty"`
+	Pending []int64 `json:"pending"`
+	Kicked  []int64 `json:"kicked"`
+	Failed  []int64 `json:"failed"`
+	Exempt  []int64 `json:"exempt"`
+
+	Users []User `json:"-"`
+}
+
+func (watcher *Watcher) overdueQuery(policy Policy) *mgo.Query {
+	return watcher.store.Find(watcher.rolloutFilter(bson.M{
+		"last_message": bson.M{
+			"$lt": time.Now().Add(policy.Duration * -1).Unix(),
+		},
+		"muted":      bson.M{"$ne": true},
+		"unbannable": bson.M{"$ne": true},
+	})).Sort("last_message", "user_id")
+}
+
+func (watcher *Watcher) kicksPerCycle() int {
+	return int(float64(watcher.kicksPerHour) * enforceInterval.Hours())
+}
+
+func (watcher *Watcher) enforce(dryRun bool) (*Summary, error) {
+	cycle := bson.NewObjectId().Hex()
+	context := karma.Describe("cycle", cycle)
+
+	policy := watcher.policy()
+
+	summary := &Summary{
+		Cycle:   cycle,
+		Time:    time.Now().Unix(),
+		DryRun:  dryRun,
+		Pending: []int64{},
+		Kicked:  []int64{},
+		Failed:  []int64{},
+		Exempt:  []int64{},
+	}
+
+	if watcher.standby {
+		log.Infof(context, "standby, skip enforcement")
+		summary.Skipped = "standby"
+		return summary, nil
+	}
+
+	if watcher.breaker.Open() {
+		log.Infof(
+			context,
+			"circuit breaker open until %v, skip enforcement",
+			watcher.breaker.OpenUntil(),
+		)
+		summary.Skipped = "circuit breaker open"
+		return summary, nil
+	}
+
+	if watcher.isPaused() {
+		log.Infof(context, "paused, skip enforcement")
+		summary.Skipped = "paused"
+		return summary, nil
+	}
+
+	if watcher.pending.Len() > 0 {
+		log.Infof(context, "activity updates are pending replay, skip enforcement")
+		summary.Skipped = "pending activity replay"
+		return summary, nil
+	}
+
+	since, err := watcher.store.Find(bson.M{
+		"last_message": bson.M{
+			"$gt": time.Now().Add(policy.Duration * -1).Unix(),
+		},
+	}).Count()
+	if err != nil {
+		return nil, context.Format(err, "find messages")
+	}
+
+	if since == 0 {
+		log.Infof(context, "no messages since %v", policy.Duration)
+		summary.Skipped = "no messages"
+		return summary, nil
+	}
+
+	resumed := false
+	if !dryRun {
+		if !atomic.CompareAndSwapInt32(&watcher.running, 0, 1) {
+			log.Infof(context, "another cycle is running, skip enforcement")
+			summary.Skipped = skippedRunning
+			return summary, nil
+		}
+
+		defer atomic.StoreInt32(&watcher.running, 0)
+
+		cycle, resumed, err = watcher.startCycle()
+		if err != nil {
+			return nil, context.Reason(err)
+		}
+
+		context = karma.Describe("cycle", cycle)
+		summary.Cycle = cycle
+
+		if resumed {
+			log.Infof(context, "resuming unfinished cycle")
+		}
+	}
+
+	query := watcher.overdueQuery(policy)
+
+	watcher.enforcing.Add(1)
+	defer watcher.enforcing.Done()
+
+	iter := query.Batch(enforceBatch).Iter()
+
+	limit := watcher.kicksPerCycle()
+	attempts := 0
+
+	for {
+		if limit > 0 && attempts >= limit {
+			log.Infof(context, "reached limit of %d kicks per cycle", limit)
+			break
+		}
+
+		var user User
+		if !iter.Next(&user) {
+			break
+		}
+
+		if watcher.isStopping() {
+			log.Infof(context, "stopping, interrupt enforcement")
+			summary.Skipped = skippedStopped
+			break
+		}
+
+		if resumed {
+			done, err := watcher.processed(cycle, user.UserID)
+			if err != nil {
+				log.Error(context.Reason(err))
+				continue
+			}
+
+			if done {
+				attempts++
+				continue
+			}
+		}
+
+		exempt, provider, err := watcher.exempt(user)
+		if err != nil {
+			log.Errorf(context.Reason(err), "check exemption of %v, skip", loggedUser(user.UserID))
+			continue
+		}
+
+		if exempt {
+			log.Infof(context, "%v is exempt by %s", loggedUser(user.UserID), provider)
+			summary.Exempt = append(summary.Exempt, user.UserID)
+			continue
+		}
+
+		attempts++
+
+		if dryRun {
+			summary.Pending = append(summary.Pending, user.UserID)
+			summary.Users = append(summary.Users, user)
+			continue
+		}
+
+		log.Infof(context, "%s %v", policy.Action, loggedUser(user.UserID))
+
+		if policy.Action == actionMute {
+			err = watcher.mute(user.UserID)
+			if err == nil {
+				err = watcher.store.Update(
+					bson.M{"user_id": user.UserID},
+					bson.M{"$set": bson.M{"muted": true}},
+				)
+			}
+		} else {
+			err = watcher.ban(user.UserID)
+		}
+		if err != nil {
+			log.Errorf(context.Reason(err), "%s %v", policy.Action, loggedUser(user.UserID))
+			summary.Failed = append(summary.Failed, user.UserID)
+
+			banErr, _ := err.(*BanError)
+
+			err = watcher.record(AuditRecord{
+				UserID:   user.UserID,
+				Username: user.Username,
+				Action:   auditError,
+				Cycle:    cycle,
+				Details:  policy.Action + ": " + err.Error(),
+			})
+			if err != nil {
+				log.Error(context.Reason(err))
+			}
+
+			if banErr == nil {
+				continue
+			}
+
+			watcher.handleUnbannable(context, cycle, user, banErr)
+
+			if banErr.Reason == banUnknown && watcher.breaker.Fail() {
+				watcher.tripBreaker(context, cycle)
+				summary.Skipped = "circuit breaker open"
+				break
+			}
+
+			if banErr.Fatal() {
+				log.Warningf(context.Reason(banErr), "interrupt enforcement")
+				summary.Skipped = banErr.Reason
+				break
+			}
+
+			if banErr.Reason == banUserNotFound {
+				err = watcher.store.Remove(bson.M{"user_id": user.UserID})
+				if err != nil {
+					log.Errorf(context.Reason(err), "remove user %v", loggedUser(user.UserID))
+				}
+			}
+
+			continue
+		}
+
+		err = watcher.record(AuditRecord{
+			UserID:   user.UserID,
+			Username: user.Username,
+			Action:   policy.Action,
+			Active:   true,
+			Cycle:    cycle,
+		})
+		if err != nil {
+			log.Error(context.Reason(err))
+		}
+
+		summary.Kicked = append(summary.Kicked, user.UserID)
+	}
+
+	err = iter.Close()
+	if err != nil {
+		return nil, context.Format(err, "find inactive users")
+	}
+
+	if !dryRun && summary.Skipped == "" && !watcher.isStopping() {
+		err = watcher.warn(context, cycle, policy)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	if !dryRun && summary.Skipped != skippedStopped {
+		err = watcher.finishCycle(cycle)
+		if err != nil {
+			log.Error(context.Reason(err))
+		}
+	}
+
+	if len(summary.Kicked) > 0 && summary.Skipped == "" && watcher.rotateInvite {
+		err = watcher.rotateInviteLink(context)
+		if err != nil {
+			log.Errorf(context.Reason(err), "rotate invite link")
+		}
+	}
+
+	log.Infof(
+		context,
+		"enforcement finished, kicked: %d failed: %d pending: %d",
+		len(summary.Kicked), len(summary.Failed), len(summary.Pending),
+	)
+
+	return summary, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/reconquest/pkg/log"
@@ -31,10 +32,66 @@ func stringEnv(key string) string {
 func durationEnv(key string) time.Duration {
 	value := stringEnv(key)
 
-	duration, err := time.ParseDuration(value)
+	duration, err := parseDuration(value)
 	if err != nil {
 		log.Fatalf(err, "parse duration: %s for %s", value, key)
 	}
 
 	return duration
 }
+
+func boolEnv(key string) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return false
+	}
+
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf(err, "parse bool: %s for %s", value, key)
+	}
+
+	return result
+}
+
+func optionalDurationEnv(key string, fallback time.Duration) time.Duration {
+	if os.Getenv(key) == "" {
+		return fallback
+	}
+
+	return durationEnv(key)
+}
+
+func optionalIntEnv(key string, fallback int) int {
+	if os.Getenv(key) == "" {
+		return fallback
+	}
+
+	return intEnv(key)
+}
+
+func optionalStringEnv(key string, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+func listEnv(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	result := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reconquest/karma-go"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const (
+	exemptPremium = "premium"
+	exemptHTTP    = "http"
+)
+
+type Exempter interface {
+	Name() string
+	Exempt(user User) (bool, error)
+}
+
+type exemptEntry struct {
+	exempt  bool
+	expires time.Time
+}
+
+type CachedExempter struct {
+	Exempter
+
+	ttl     time.Duration
+	entries map[int64]exemptEntry
+	mutex   sync.Mutex
+}
+
+func NewCachedExempter(exempter Exempter, ttl time.Duration) *CachedExempter {
+	return &CachedExempter{
+		Exempter: exempter,
+		ttl:      ttl,
+		entries:  map[int64]exemptEntry{},
+	}
+}
+
+func (cache *CachedExempter) Exempt(user User) (bool, error) {
+	cache.mutex.Lock()
+	entry, ok := cache.entries[user.UserID]
+	cache.mutex.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.exempt, nil
+	}
+
+	exempt, err := cache.Exempter.Exempt(user)
+	if err != nil {
+		return false, err
+	}
+
+	cache.mutex.Lock()
+	cache.entries[user.UserID] = exemptEntry{
+		exempt:  exempt,
+		expires: time.Now().Add(cache.ttl),
+	}
+	cache.mutex.Unlock()
+
+	return exempt, nil
+}
+
+type PremiumExempter struct {
+	watcher *Watcher
+}
+
+func (exempter *PremiumExempter) Name() string {
+	return exemptPremium
+}
+
+func (exempter *PremiumExempter) Exempt(user User) (bool, error) {
+	member, err := exempter.watcher.bot.ChatMemberOf(
+		exempter.watcher.getChat(),
+		&telebot.User{ID: user.UserID},
+	)
+	if err != nil {
+		return false, karma.Format(err, "get chat member: %v", loggedUser(user.UserID))
+	}
+
+	return member.User != nil && member.User.IsPremium, nil
+}
+
+type HTTPExempter struct {
+	url  string
+	ttl  time.Duration
+	list map[string]bool
+
+	fetched time.Time
+	mutex   sync.Mutex
+}
+
+func (exempter *HTTPExempter) Name() string {
+	return exemptHTTP
+}
+
+func (exempter *HTTPExempter) fetch() (map[string]bool, error) {
+	response, err := http.Get(exempter.url)
+	if err != nil {
+		return nil, karma.Format(err, "get exempt list: %s", exempter.url)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, karma.Format(
+			nil,
+			"get exempt list: %s: unexpected status %s",
+			exempter.url, response.Status,
+		)
+	}
+
+	list := map[string]bool{}
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		entry := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "@")
+		if entry != "" {
+			list[strings.ToLower(entry)] = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, karma.Format(err, "read exempt list: %s", exempter.url)
+	}
+
+	return list, nil
+}
+
+func (exempter *HTTPExempter) Exempt(user User) (bool, error) {
+	exempter.mutex.Lock()
+	defer exempter.mutex.Unlock()
+
+	if exempter.list == nil || time.Since(exempter.fetched) > exempter.ttl {
+		list, err := exempter.fetch()
+		if err != nil {
+			return false, err
+		}
+
+		exempter.list = list
+		exempter.fetched = time.Now()
+	}
+
+	return exempter.list[strconv.FormatInt(user.UserID, 10)] ||
+		(user.Username != "" && exempter.list[strings.ToLower(user.Username)]), nil
+}
+
+func (watcher *Watcher) newExempter(
+	name string,
+	url string,
+	ttl time.Duration,
+) (Exempter, error) {
+	switch name {
+	case exemptPremium:
+		return NewCachedExempter(
+			&PremiumExempter{watcher: watcher},
+			ttl,
+		), nil
+
+	case exemptHTTP:
+		if url == "" {
+			return nil, karma.Format(nil, "no env %q specified", "EXEMPT_URL")
+		}
+
+		return &HTTPExempter{url: url, ttl: ttl}, nil
+	}
+
+	return nil, karma.Format(
+		nil,
+		"unknown exemption provider %q, available: %s, %s",
+		name, exemptPremium, exemptHTTP,
+	)
+}
+
+func (watcher *Watcher) exempt(user User) (bool, string, error) {
+	for _, exempter := range watcher.exempters {
+		exempt, err := exempter.Exempt(user)
+		if err != nil {
+			return false, "", karma.Format(err, "exemption provider %s", exempter.Name())
+		}
+
+		if exempt {
+			return true, exempter.Name(), nil
+		}
+	}
+
+	return false, "", nil
+}
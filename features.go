@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	telebot "gopkg.in/telebot.v3"
+)
+
+type Feature struct {
+	Name    string
+	Enabled bool
+	Details string
+}
+
+func (watcher *Watcher) features() []Feature {
+	exempters := []string{}
+	for _, exempter := range watcher.exempters {
+		exempters = append(exempters, exempter.Name())
+	}
+
+	return []Feature{
+		{"standby", watcher.standby, ""},
+		{"leader-lock", watcher.lockTTL > 0, watcher.lockTTL.String()},
+		{"settings-reload", watcher.settingsReload > 0, watcher.settingsReload.String()},
+		{"owner-console", watcher.owner != 0, fmt.Sprint(watcher.owner)},
+		{"metrics", watcher.metricsListen != "", watcher.metricsListen},
+		{"backups", watcher.backuper != nil, ""},
+		{"audit-retention", watcher.auditRetention > 0, watcher.auditRetention.String()},
+		{"history-retention", watcher.historyRetention > 0, watcher.historyRetention.String()},
+		{"snapshot-retention", watcher.snapshotRetention > 0, watcher.snapshotRetention.String()},
+		{"rollout-since", !watcher.rolloutSince.IsZero(), watcher.rolloutSince.Format("2006-01-02")},
+		{"rollout-percent", watcher.rolloutPercent > 0, fmt.Sprint(watcher.rolloutPercent)},
+		{"circuit-breaker", watcher.breaker.threshold > 0, fmt.Sprint(watcher.breaker.threshold)},
+		{"vouching", watcher.vouchThreshold > 0, fmt.Sprint(watcher.vouchThreshold)},
+		{"digest", watcher.digestInterval > 0, watcher.digestInterval.String()},
+		{"warnings", watcher.policy().WarnBefore > 0, ""},
+		{"mute", watcher.policy().Action == actionMute, ""},
+		{"kick-rate-limit", watcher.kicksPerHour > 0, fmt.Sprint(watcher.kicksPerHour)},
+		{"initial-credit", watcher.initialCredit > 0, watcher.initialCredit.String()},
+		{"rejoin-grace", watcher.rejoinGrace > 0, watcher.rejoinGrace.String()},
+		{"join-requests", watcher.joinKicks > 0, watcher.joinAction},
+		{"exemptions", len(exempters) > 0, strings.Join(exempters, ",")},
+		{"rename-detection", watcher.renameAction != "", watcher.renameAction},
+		{"rotate-invite", watcher.rotateInvite, ""},
+		{"pardon-invite", watcher.pardonInvite, ""},
+		{"when-bucketed", watcher.whenBucketed, ""},
+	}
+}
+
+func (watcher *Watcher) listFeatures() string {
+	lines := []string{}
+	for _, feature := range watcher.features() {
+		state := "off"
+		if feature.Enabled {
+			state = "on"
+			if feature.Details != "" {
+				state += " (" + feature.Details + ")"
+			}
+		}
+
+		lines = append(lines, feature.Name+": "+state)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (watcher *Watcher) handleFeatures(context telebot.Context) error {
+	return context.Reply(watcher.listFeatures())
+}
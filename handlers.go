@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+	"gopkg.in/telebot.v3/middleware"
+)
+
+const standbyNotice = "This instance is in standby mode and is read-only."
+
+var activityEndpoints = []string{
+	telebot.OnText,
+	telebot.OnMedia,
+	telebot.OnContact,
+	telebot.OnLocation,
+	telebot.OnVenue,
+	telebot.OnGame,
+	telebot.OnDice,
+	telebot.OnInvoice,
+	telebot.OnPayment,
+}
+
+func (watcher *Watcher) route() {
+	bot := watcher.bot
+
+	bot.Use(
+		watcher.handoffMiddleware,
+		watcher.recoverMiddleware,
+		watcher.logMiddleware,
+	)
+
+	commands := bot.Group()
+	commands.Use(watcher.commandChatMiddleware, watcher.usage.Middleware)
+
+	commands.Handle("/when", watcher.handleWhen, watcher.whenCooldown.Middleware)
+	commands.Handle("q", watcher.handleWhen, watcher.whenCooldown.Middleware)
+	commands.Handle("/settings", watcher.handleSettings)
+	commands.Handle("/stats", watcher.handleStats)
+	commands.Handle("/about", watcher.handleAbout)
+	commands.Handle("/start", watcher.handleStart)
+	commands.Handle("/why", watcher.handleWhy)
+	commands.Handle("/hideme", watcher.handleHideMe)
+	commands.Handle("/showme", watcher.handleShowMe)
+
+	admin := bot.Group()
+	admin.Use(
+		watcher.commandChatMiddleware,
+		watcher.usage.Middleware,
+		watcher.adminMiddleware,
+	)
+
+	admin.Handle("/pardon", watcher.handlePardon, watcher.standbyMiddleware)
+	admin.Handle("/unreachable", watcher.handleUnreachable)
+	admin.Handle("/pause", watcher.handlePause, watcher.standbyMiddleware)
+	admin.Handle("/resume", watcher.handleResume, watcher.standbyMiddleware)
+	admin.Handle("/dryrun", watcher.handleDryRun)
+	admin.Handle("/topic", watcher.handleTopic, watcher.standbyMiddleware)
+	admin.Handle("/log", watcher.handleLog)
+	admin.Handle("/features", watcher.handleFeatures)
+	admin.Handle("/usage", watcher.handleUsage)
+	admin.Handle("/queue", watcher.handleQueue)
+
+	callbacks := bot.Group()
+	callbacks.Use(
+		middleware.AutoRespond(),
+		watcher.adminMiddleware,
+		watcher.standbyMiddleware,
+	)
+
+	callbacks.Handle(&btnJoinApprove, watcher.handleJoinApprove)
+	callbacks.Handle(&btnJoinDecline, watcher.handleJoinDecline)
+	callbacks.Handle(&btnQueueCancel, watcher.handleQueueCancel)
+
+	bot.Handle(
+		&btnVouch,
+		watcher.handleVouch,
+		watcher.chatMiddleware,
+		watcher.standbyMiddleware,
+	)
+
+	bot.Handle(telebot.OnChatJoinRequest, watcher.handleJoinRequest)
+	bot.Handle(telebot.OnMigration, watcher.handleMigration)
+
+	activity := bot.Group()
+	activity.Use(watcher.consoleMiddleware, watcher.chatMiddleware)
+
+	activity.Handle(telebot.OnUserLeft, watcher.handleUserLeft)
+	activity.Handle(telebot.OnUserJoined, watcher.handleUserJoined)
+
+	for _, endpoint := range activityEndpoints {
+		activity.Handle(endpoint, watcher.handleActivity)
+	}
+
+	for _, endpoint := range serviceEndpoints {
+		activity.Handle(endpoint, watcher.handleServiceMessage(endpoint))
+	}
+}
+
+func (watcher *Watcher) recoverMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(context telebot.Context) (err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = fmt.Errorf("panic: %v", recovered)
+			}
+		}()
+
+		return next(context)
+	}
+}
+
+func (watcher *Watcher) logMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(context telebot.Context) error {
+		var sender int64
+		if context.Sender() != nil {
+			sender = context.Sender().ID
+		}
+
+		var chat int64
+		if context.Chat() != nil {
+			chat = context.Chat().ID
+		}
+
+		log.Debugf(nil, "update: %v chat: %v sender: %v",
+			context.Update().ID, chat, loggedUser(sender))
+
+		return next(context)
+	}
+}
+
+func (watcher *Watcher) chatMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(context telebot.Context) error {
+		if context.Chat() == nil || context.Chat().ID != watcher.getChat().ID {
+			return nil
+		}
+
+		return next(context)
+	}
+}
+
+func (watcher *Watcher) commandChatMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(context telebot.Context) error {
+		if context.Chat() == nil {
+			return nil
+		}
+
+		if context.Chat().ID != watcher.getChat().ID && !context.Message().Private() {
+			return nil
+		}
+
+		return next(context)
+	}
+}
+
+func (watcher *Watcher) adminMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(context telebot.Context) error {
+		admin, err := watcher.isAdmin(context.Sender())
+		if err != nil {
+			return err
+		}
+
+		if !admin {
+			if context.Callback() != nil {
+				return context.Respond(&telebot.CallbackResponse{
+					Text: "This action is for admins only.",
+				})
+			}
+
+			return context.Reply("This command is for admins only.")
+		}
+
+		return next(context)
+	}
+}
+
+func (watcher *Watcher) standbyMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(context telebot.Context) error {
+		if !watcher.standby {
+			return next(context)
+		}
+
+		if context.Callback() != nil {
+			return context.Respond(&telebot.CallbackResponse{Text: standbyNotice})
+		}
+
+		return context.Reply(standbyNotice)
+	}
+}
+
+func onError(err error, context telebot.Context) {
+	if context != nil && context.Sender() != nil {
+		log.Errorf(err, "handle update from: %v", loggedUser(context.Sender().ID))
+		return
+	}
+
+	log.Error(err)
+}
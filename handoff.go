@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+type Lock struct {
+	ChatID  int64  `bson:"chat_id"`
+	Owner   string `bson:"owner"`
+	Expires int64  `bson:"expires"`
+	Offset  int    `bson:"offset"`
+}
+
+func instanceID() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s/%d/%s", hostname, os.Getpid(), bson.NewObjectId().Hex())
+}
+
+func (watcher *Watcher) tryLock() (bool, error) {
+	_, err := watcher.locks.Upsert(
+		bson.M{
+			"chat_id": watcher.getChat().ID,
+			"$or": []bson.M{
+				{"owner": watcher.instance},
+				{"expires": bson.M{"$lt": time.Now().Unix()}},
+			},
+		},
+		bson.M{"$set": bson.M{
+			"owner":   watcher.instance,
+			"expires": time.Now().Add(watcher.lockTTL).Unix(),
+		}},
+	)
+	if err != nil {
+		if mgo.IsDup(err) {
+			return false, nil
+		}
+
+		return false, karma.Format(err, "upsert leader lock")
+	}
+
+	return true, nil
+}
+
+func (watcher *Watcher) acquireLeader() (*Lock, error) {
+	err := watcher.locks.EnsureIndex(mgo.Index{
+		Key:    []string{"chat_id"},
+		Unique: true,
+	})
+	if err != nil {
+		return nil, karma.Format(err, "ensure leader lock index")
+	}
+
+	for waiting := false; ; waiting = true {
+		locked, err := watcher.tryLock()
+		if err != nil {
+			return nil, err
+		}
+
+		if locked {
+			break
+		}
+
+		if !waiting {
+			log.Infof(nil, "waiting for the leader lock to be released")
+		}
+
+		time.Sleep(time.Second)
+	}
+
+	var lock Lock
+	err = watcher.locks.Find(bson.M{"chat_id": watcher.getChat().ID}).One(&lock)
+	if err != nil {
+		return nil, karma.Format(err, "find leader lock")
+	}
+
+	log.Infof(nil, "acquired leader lock, update offset: %d", lock.Offset)
+
+	go watcher.renewLeader()
+
+	return &lock, nil
+}
+
+func (watcher *Watcher) renewLeader() {
+	for range time.Tick(watcher.lockTTL / 3) {
+		if watcher.isStopping() {
+			return
+		}
+
+		locked, err := watcher.tryLock()
+		if err != nil {
+			log.Errorf(err, "renew leader lock")
+			continue
+		}
+
+		if !locked {
+			log.Fatalf(nil, "leader lock was taken over by another instance")
+		}
+	}
+}
+
+func (watcher *Watcher) releaseLeader(offset int) error {
+	err := watcher.locks.Update(
+		bson.M{"chat_id": watcher.getChat().ID, "owner": watcher.instance},
+		bson.M{"$set": bson.M{"expires": 0, "offset": offset}},
+	)
+	if err != nil {
+		return karma.Format(err, "release leader lock")
+	}
+
+	log.Infof(nil, "released leader lock, update offset: %d", offset)
+
+	return nil
+}
+
+func (watcher *Watcher) handoffMiddleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(context telebot.Context) error {
+		watcher.handling.Add(1)
+		defer watcher.handling.Done()
+
+		defer watcher.markProcessed(context.Update().ID)
+
+		return next(context)
+	}
+}
+
+func (watcher *Watcher) markProcessed(update int) {
+	for {
+		last := atomic.LoadInt64(&watcher.lastProcessed)
+		if int64(update) <= last {
+			return
+		}
+
+		if atomic.CompareAndSwapInt64(&watcher.lastProcessed, last, int64(update)) {
+			return
+		}
+	}
+}
+
+func (watcher *Watcher) dispatch() {
+	bot := watcher.bot
+
+	stop := make(chan struct{})
+	go bot.Poller.Poll(bot, bot.Updates, stop)
+
+	for {
+		select {
+		case update := <-bot.Updates:
+			bot.ProcessUpdate(update)
+
+		case confirm := <-watcher.finish:
+			for len(bot.Updates) > 0 {
+				bot.ProcessUpdate(<-bot.Updates)
+			}
+
+			close(stop)
+			close(confirm)
+
+			return
+		}
+	}
+}
+
+func (watcher *Watcher) handoff(watchdog *WatchdogPoller) {
+	watchdog.Drain()
+
+	confirm := make(chan struct{})
+	watcher.finish <- confirm
+	<-confirm
+
+	watcher.handling.Wait()
+}
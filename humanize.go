@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	languageEnglish = "en"
+	languageRussian = "ru"
+)
+
+var languages = []string{languageEnglish, languageRussian}
+
+type durationUnit struct {
+	size    time.Duration
+	english [2]string
+	russian [3]string
+}
+
+var durationUnits = []durationUnit{
+	{day, [2]string{"day", "days"}, [3]string{"день", "дня", "дней"}},
+	{time.Hour, [2]string{"hour", "hours"}, [3]string{"час", "часа", "часов"}},
+	{time.Minute, [2]string{"minute", "minutes"}, [3]string{"минута", "минуты", "минут"}},
+}
+
+var russianAuditActions = map[string]string{
+	auditKick:        "исключение",
+	auditMute:        "запрет писать",
+	auditPardon:      "помилование",
+	auditJoinApprove: "заявка одобрена",
+	auditJoinDecline: "заявка отклонена",
+	auditRename:      "смена имени",
+	auditWarn:        "предупреждение",
+	auditSaved:       "ответил после предупреждения",
+	auditError:       "ошибка",
+	auditRemove:      "удалён админом",
+	auditRejoin:      "вернулся",
+	auditSettings:    "настройки",
+	auditCancel:      "отмена исключения",
+	auditVouch:       "поручительство",
+}
+
+var auditActorPrefixes = map[string]string{
+	languageEnglish: "by",
+	languageRussian: "от",
+}
+
+var emptyLogTexts = map[string]string{
+	languageEnglish: "The log is empty.",
+	languageRussian: "Журнал пуст.",
+}
+
+var emptySettingsHistoryTexts = map[string]string{
+	languageEnglish: "Settings were never changed.",
+	languageRussian: "Настройки ещё не менялись.",
+}
+
+var rejoinGreetingTexts = map[string]string{
+	languageEnglish: "Welcome back, %s! You were removed for inactivity before, " +
+		"so you have %s to say hello.",
+	languageRussian: "С возвращением, %s! Раньше вас удалили за неактивность, " +
+		"поэтому у вас есть %s, чтобы поздороваться.",
+}
+
+var pausedTexts = map[string]string{
+	languageEnglish: "Paused for %s.",
+	languageRussian: "Пауза на %s.",
+}
+
+type whyText struct {
+	unseen      string
+	noMessages  string
+	lastMessage string
+	grace       string
+	warned      string
+	muted       string
+	exempt      string
+	deadline    string
+}
+
+var whyTexts = map[string]whyText{
+	languageEnglish: {
+		unseen:      "I have not seen any messages from this user yet.",
+		noMessages:  "No messages counted yet.",
+		lastMessage: "Last message: %s.",
+		grace:       "Rejoin grace period until %s.",
+		warned:      "Warned at %s.",
+		muted:       "Muted for inactivity.",
+		exempt:      "Exempt from inactivity enforcement (%s).",
+		deadline:    "Will be %s after %s without messages.",
+	},
+	languageRussian: {
+		unseen:      "Я ещё не видел сообщений от этого пользователя.",
+		noMessages:  "Сообщений пока нет.",
+		lastMessage: "Последнее сообщение: %s.",
+		grace:       "Льготный период после возвращения до %s.",
+		warned:      "Предупреждён %s.",
+		muted:       "Лишён права писать за неактивность.",
+		exempt:      "Не подпадает под проверку активности (%s).",
+		deadline:    "Если не писать до %[2]s: %[1]s.",
+	},
+}
+
+func auditActionName(action string, language string) string {
+	if language == languageRussian {
+		if name, ok := russianAuditActions[action]; ok {
+			return name
+		}
+	}
+
+	return action
+}
+
+func supportedLanguage(code string) string {
+	code = strings.ToLower(strings.SplitN(code, "-", 2)[0])
+	for _, language := range languages {
+		if code == language {
+			return language
+		}
+	}
+
+	return ""
+}
+
+func pluralRussian(n int64, forms [3]string) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return forms[0]
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 10 || n%100 >= 20):
+		return forms[1]
+	default:
+		return forms[2]
+	}
+}
+
+func formatUnit(n int64, unit durationUnit, language string) string {
+	if language == languageRussian {
+		return fmt.Sprintf("%d %s", n, pluralRussian(n, unit.russian))
+	}
+
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit.english[0])
+	}
+
+	return fmt.Sprintf("%d %s", n, unit.english[1])
+}
+
+func humanDuration(duration time.Duration, language string) string {
+	if duration < 0 {
+		duration = 0
+	}
+
+	parts := []string{}
+	for _, unit := range durationUnits {
+		n := int64(duration / unit.size)
+		if n == 0 {
+			if len(parts) > 0 {
+				break
+			}
+
+			continue
+		}
+
+		parts = append(parts, formatUnit(n, unit, language))
+		duration -= time.Duration(n) * unit.size
+
+		if len(parts) == 2 {
+			break
+		}
+	}
+
+	if len(parts) == 0 {
+		return formatUnit(0, durationUnits[len(durationUnits)-1], language)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func signedDuration(duration time.Duration, language string) string {
+	if duration < 0 {
+		return "-" + humanDuration(-duration, language)
+	}
+
+	return "+" + humanDuration(duration, language)
+}
+
+func humanAgo(duration time.Duration, language string) string {
+	if duration < 0 {
+		if language == languageRussian {
+			return "сообщений пока нет"
+		}
+
+		return "no messages counted yet"
+	}
+
+	if language == languageRussian {
+		return humanDuration(duration, language) + " назад"
+	}
+
+	return humanDuration(duration, language) + " ago"
+}
+
+func (watcher *Watcher) chatLanguage() string {
+	if language := watcher.getSettings().Language; language != "" {
+		return language
+	}
+
+	return languageEnglish
+}
+
+func (watcher *Watcher) userLanguage(code string) string {
+	if language := supportedLanguage(code); language != "" {
+		return language
+	}
+
+	return watcher.chatLanguage()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPluralRussian(t *testing.T) {
+	forms := [3]string{"день", "дня", "дней"}
+
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "дней"},
+		{1, "день"},
+		{2, "дня"},
+		{4, "дня"},
+		{5, "дней"},
+		{11, "дней"},
+		{12, "дней"},
+		{14, "дней"},
+		{19, "дней"},
+		{21, "день"},
+		{22, "дня"},
+		{25, "дней"},
+		{101, "день"},
+		{111, "дней"},
+		{112, "дней"},
+		{122, "дня"},
+	}
+
+	for _, test := range tests {
+		got := pluralRussian(test.n, forms)
+		if got != test.want {
+			t.Errorf("%d: got %q, want %q", test.n, got, test.want)
+		}
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		language string
+		want     string
+	}{
+		{0, languageEnglish, "0 minutes"},
+		{-time.Hour, languageEnglish, "0 minutes"},
+		{30 * time.Second, languageEnglish, "0 minutes"},
+		{time.Minute, languageEnglish, "1 minute"},
+		{time.Hour + 5*time.Minute, languageEnglish, "1 hour 5 minutes"},
+		{day + time.Minute, languageEnglish, "1 day"},
+		{2*day + 3*time.Hour + 4*time.Minute, languageEnglish, "2 days 3 hours"},
+		{21*day + 2*time.Hour, languageRussian, "21 день 2 часа"},
+		{5 * time.Hour, languageRussian, "5 часов"},
+		{0, languageRussian, "0 минут"},
+	}
+
+	for _, test := range tests {
+		got := humanDuration(test.duration, test.language)
+		if got != test.want {
+			t.Errorf("%v %s: got %q, want %q", test.duration, test.language, got, test.want)
+		}
+	}
+}
+
+func TestHumanAgo(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		language string
+		want     string
+	}{
+		{3 * day, languageEnglish, "3 days ago"},
+		{3 * day, languageRussian, "3 дня назад"},
+		{-7 * day, languageEnglish, "no messages counted yet"},
+		{-7 * day, languageRussian, "сообщений пока нет"},
+	}
+
+	for _, test := range tests {
+		got := humanAgo(test.duration, test.language)
+		if got != test.want {
+			t.Errorf("%v %s: got %q, want %q", test.duration, test.language, got, test.want)
+		}
+	}
+}
+
+func TestSignedDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		language string
+		want     string
+	}{
+		{2 * day, languageEnglish, "+2 days"},
+		{-3 * time.Hour, languageEnglish, "-3 hours"},
+		{-day, languageRussian, "-1 день"},
+		{0, languageEnglish, "+0 minutes"},
+	}
+
+	for _, test := range tests {
+		got := signedDuration(test.duration, test.language)
+		if got != test.want {
+			t.Errorf("%v %s: got %q, want %q", test.duration, test.language, got, test.want)
+		}
+	}
+}
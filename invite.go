@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+)
+
+func (watcher *Watcher) rotateInviteLink(context *karma.Context) error {
+	chat, err := watcher.bot.ChatByID(watcher.getChat().ID)
+	if err != nil {
+		return karma.Format(err, "get chat: %v", watcher.getChat().ID)
+	}
+
+	if chat.InviteLink != "" {
+		_, err = watcher.bot.RevokeInviteLink(watcher.getChat(), chat.InviteLink)
+		if err != nil {
+			log.Errorf(context.Reason(err), "revoke invite link")
+		}
+	}
+
+	_, err = watcher.bot.InviteLink(watcher.getChat())
+	if err != nil {
+		return karma.Format(err, "export invite link")
+	}
+
+	log.Infof(context, "invite link rotated")
+
+	return nil
+}
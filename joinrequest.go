@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const (
+	joinActionDecline = "decline"
+	joinActionAdmins  = "admins"
+)
+
+var (
+	joinMarkup     = &telebot.ReplyMarkup{}
+	btnJoinApprove = joinMarkup.Data("Approve", "join_approve")
+	btnJoinDecline = joinMarkup.Data("Decline", "join_decline")
+)
+
+func (watcher *Watcher) handleJoinRequest(context telebot.Context) error {
+	request := context.ChatJoinRequest()
+	if request.Chat.ID != watcher.getChat().ID ||
+		watcher.joinKicks == 0 ||
+		watcher.standby {
+		return nil
+	}
+
+	var history []AuditRecord
+	err := watcher.audit.Find(bson.M{
+		"user_id": request.Sender.ID,
+		"action":  auditKick,
+	}).Sort("time").All(&history)
+	if err != nil {
+		return karma.Format(err, "find kick history: %v", loggedUser(request.Sender.ID))
+	}
+
+	if len(history) < watcher.joinKicks {
+		return nil
+	}
+
+	log.Infof(
+		nil,
+		"join request from user: %v kicked %d times, action: %s",
+		loggedUser(request.Sender.ID), len(history), watcher.joinAction,
+	)
+
+	if watcher.joinAction == joinActionDecline {
+		return watcher.declineJoinRequest(request.Sender, 0)
+	}
+
+	return watcher.routeJoinRequest(request.Sender, history)
+}
+
+func (watcher *Watcher) routeJoinRequest(
+	user *telebot.User,
+	history []AuditRecord,
+) error {
+	admins, err := watcher.bot.AdminsOf(watcher.getChat())
+	if err != nil {
+		return karma.Format(err, "get chat admins")
+	}
+
+	kicks := []string{}
+	for _, record := range history {
+		kicks = append(
+			kicks,
+			time.Unix(record.Time, 0).Format(time.RFC1123),
+		)
+	}
+
+	text := fmt.Sprintf(
+		"Join request from @%s %s %s (%v) who was kicked for inactivity %d times:\n%s",
+		user.Username, user.FirstName, user.LastName, user.ID,
+		len(history),
+		strings.Join(kicks, "\n"),
+	)
+
+	id := strconv.FormatInt(user.ID, 10)
+
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(btnJoinApprove.Text, btnJoinApprove.Unique, id),
+		markup.Data(btnJoinDecline.Text, btnJoinDecline.Unique, id),
+	))
+
+	for _, admin := range admins {
+		if admin.User.IsBot {
+			continue
+		}
+
+		_, err := watcher.sendPrivate(admin.User, text, markup)
+		if err != nil {
+			log.Errorf(err, "send join request to admin: %v", loggedUser(admin.User.ID))
+		}
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) handleJoinApprove(context telebot.Context) error {
+	user, err := strconv.ParseInt(context.Data(), 10, 64)
+	if err != nil {
+		return karma.Format(err, "parse user id: %s", context.Data())
+	}
+
+	err = watcher.bot.ApproveJoinRequest(
+		watcher.getChat(),
+		&telebot.User{ID: user},
+	)
+	if err != nil {
+		return karma.Format(err, "approve join request: %v", loggedUser(user))
+	}
+
+	err = watcher.record(AuditRecord{
+		UserID: user,
+		Action: auditJoinApprove,
+		Actor:  context.Sender().ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return context.Edit(
+		context.Message().Text + "\n\nApproved by @" + context.Sender().Username,
+	)
+}
+
+func (watcher *Watcher) handleJoinDecline(context telebot.Context) error {
+	user, err := strconv.ParseInt(context.Data(), 10, 64)
+	if err != nil {
+		return karma.Format(err, "parse user id: %s", context.Data())
+	}
+
+	err = watcher.declineJoinRequest(&telebot.User{ID: user}, context.Sender().ID)
+	if err != nil {
+		return err
+	}
+
+	return context.Edit(
+		context.Message().Text + "\n\nDeclined by @" + context.Sender().Username,
+	)
+}
+
+func (watcher *Watcher) declineJoinRequest(user *telebot.User, actor int64) error {
+	err := watcher.bot.DeclineJoinRequest(watcher.getChat(), user)
+	if err != nil {
+		return karma.Format(err, "decline join request: %v", loggedUser(user.ID))
+	}
+
+	return watcher.record(AuditRecord{
+		UserID:   user.ID,
+		Username: user.Username,
+		Action:   auditJoinDecline,
+		Actor:    actor,
+	})
+}
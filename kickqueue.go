@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const queueMax = 20
+
+var (
+	queueMarkup    = &telebot.ReplyMarkup{}
+	btnQueueCancel = queueMarkup.Data("Cancel", "queue_cancel")
+)
+
+func (watcher *Watcher) renderQueue() (string, *telebot.ReplyMarkup, error) {
+	policy := watcher.policy()
+
+	limit := queueMax
+	if cycle := watcher.kicksPerCycle(); cycle > 0 && cycle < limit {
+		limit = cycle
+	}
+
+	users := []User{}
+
+	iter := watcher.overdueQuery(policy).Batch(enforceBatch).Iter()
+
+	for len(users) < limit {
+		var user User
+		if !iter.Next(&user) {
+			break
+		}
+
+		exempt, _, err := watcher.exempt(user)
+		if err != nil {
+			log.Errorf(err, "check exemption of %v, skip", loggedUser(user.UserID))
+			continue
+		}
+
+		if !exempt {
+			users = append(users, user)
+		}
+	}
+
+	err := iter.Close()
+	if err != nil {
+		return "", nil, karma.Format(err, "find overdue users")
+	}
+
+	markup := &telebot.ReplyMarkup{}
+
+	if len(users) == 0 {
+		return "Nobody is going to be " + actionVerb(policy.Action) +
+			" in the next cycle.", markup, nil
+	}
+
+	lines := []string{
+		fmt.Sprintf(
+			"Going to be %s at %s:",
+			actionVerb(policy.Action),
+			watcher.getNextEnforce().Format(time.RFC1123),
+		),
+	}
+
+	language := watcher.chatLanguage()
+
+	rows := []telebot.Row{}
+	for _, user := range users {
+		name := displayName(user.Username, user.FirstName, user.LastName)
+
+		lines = append(lines, fmt.Sprintf(
+			"%s (%v), idle %s",
+			name, user.UserID,
+			humanDuration(time.Since(time.Unix(user.LastMessage, 0)), language),
+		))
+
+		rows = append(rows, markup.Row(markup.Data(
+			btnQueueCancel.Text+" "+name,
+			btnQueueCancel.Unique,
+			strconv.FormatInt(user.UserID, 10),
+		)))
+	}
+
+	markup.Inline(rows...)
+
+	return strings.Join(lines, "\n"), markup, nil
+}
+
+func (watcher *Watcher) handleQueue(context telebot.Context) error {
+	text, markup, err := watcher.renderQueue()
+	if err != nil {
+		return err
+	}
+
+	return context.Reply(text, markup)
+}
+
+func (watcher *Watcher) handleQueueCancel(context telebot.Context) error {
+	user, err := strconv.ParseInt(context.Data(), 10, 64)
+	if err != nil {
+		return karma.Format(err, "parse user id: %s", context.Data())
+	}
+
+	log.Infof(nil, "queued %v cancelled by %v", loggedUser(user), loggedUser(context.Sender().ID))
+
+	err = watcher.store.Update(
+		bson.M{"user_id": user},
+		bson.M{"$set": bson.M{
+			"last_message": time.Now().Unix(),
+			"warned_at":    0,
+		}},
+	)
+	if err != nil {
+		return karma.Format(err, "reset activity: %v", loggedUser(user))
+	}
+
+	err = watcher.record(AuditRecord{
+		UserID: user,
+		Action: auditCancel,
+		Actor:  context.Sender().ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	text, markup, err := watcher.renderQueue()
+	if err != nil {
+		return err
+	}
+
+	return context.Edit(text, markup)
+}
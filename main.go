@@ -7,20 +7,43 @@ import (
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/docopt/docopt-go"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/reconquest/karma-go"
 	"github.com/reconquest/pkg/log"
 	telebot "gopkg.in/telebot.v3"
 )
 
 type User struct {
-	UserID      int64 `bson:"user_id"`
-	LastMessage int64 `bson:"last_message"`
+	UserID      int64   `bson:"user_id"`
+	Username    string  `bson:"username,omitempty"`
+	FirstName   string  `bson:"first_name,omitempty"`
+	LastName    string  `bson:"last_name,omitempty"`
+	LastMessage int64   `bson:"last_message"`
+	FirstSeen   int64   `bson:"first_seen"`
+	JoinedAt    int64   `bson:"joined_at,omitempty"`
+	Messages    int64   `bson:"messages"`
+	Messages30d int64   `bson:"messages_30d"`
+	Unreachable bool    `bson:"unreachable"`
+	Hidden      bool    `bson:"hidden"`
+	Flagged     bool    `bson:"flagged"`
+	Muted       bool    `bson:"muted"`
+	WarnedAt    int64   `bson:"warned_at"`
+	GraceUntil  int64   `bson:"grace_until,omitempty"`
+	Language    string  `bson:"language,omitempty"`
+	Unbannable  bool    `bson:"unbannable,omitempty"`
+	Vouchers    []int64 `bson:"vouchers,omitempty"`
+	VouchedAt   int64   `bson:"vouched_at,omitempty"`
+
+	MessageDays map[string]int64 `bson:"message_days,omitempty"`
 }
 
 var (
@@ -29,21 +52,187 @@ var (
 
 Usage:
   telekick [options]
+  telekick run-once [options] [--dry-run]
+  telekick restore <backup> [options]
+  telekick broadcast --file=<path> [options] [--only-at-risk]
+  telekick diff [options] [--since=<period>]
+  telekick config export [options]
+  telekick config apply <manifest> [options]
+  telekick query <expression> [options]
   telekick -h | --help
   telekick --version
 
 Options:
-  -S --stats  Show stats.
-  -h --help   Show this screen.
-  --version   Show version.
+  -S --stats            Show stats.
+  --features            Show which optional features are enabled.
+  --standby             Record activity and answer read-only commands, but
+                         never warn, kick, change settings or post to the chat.
+  --dry-run             Do not kick anyone, only report who would be kicked.
+  --report=<format>     Report format, csv or json. [default: csv]
+  --format=<format>     Query output format, same as --report.
+  --report-to=<chat>    Send dry run report to this Telegram chat or user.
+  --file=<path>         File with the message to broadcast.
+  --only-at-risk        Broadcast only to users close to the inactivity limit.
+  --since=<period>      Membership diff period, e.g. 7d or 12h. [default: 7d]
+  -h --help             Show this screen.
+  --version             Show version.
+
+Settings applied with "config apply" are picked up by running instances
+within SETTINGS_RELOAD (1m by default).
 `
 )
 
+const startWhen = "when"
+
+var commands = []telebot.Command{
+	{
+		Text:        "when",
+		Description: "Show the list of users and number of hours since their last message",
+	},
+	{
+		Text:        "pardon",
+		Description: "Unban a kicked user and reset their timer (admins only)",
+	},
+	{
+		Text:        "unreachable",
+		Description: "Show users the bot cannot message privately (admins only)",
+	},
+	{
+		Text:        "pause",
+		Description: "Suspend activity tracking and kicks for a duration (admins only)",
+	},
+	{
+		Text:        "resume",
+		Description: "Resume activity tracking and kicks (admins only)",
+	},
+	{
+		Text:        "settings",
+		Description: "Show current settings and status",
+	},
+	{
+		Text:        "hideme",
+		Description: "Hide yourself from public activity listings",
+	},
+	{
+		Text:        "showme",
+		Description: "Show yourself in public activity listings again",
+	},
+	{
+		Text:        "stats",
+		Description: "Show aggregated community activity figures",
+	},
+	{
+		Text:        "dryrun",
+		Description: "Receive a file with users who would be kicked now (admins only)",
+	},
+	{
+		Text:        "topic",
+		Description: "Set how messages in this forum topic count: normal, ignore or double (admins only)",
+	},
+	{
+		Text:        "why",
+		Description: "Explain when and why you would be kicked",
+	},
+	{
+		Text:        "features",
+		Description: "Show which optional features are enabled (admins only)",
+	},
+	{
+		Text:        "queue",
+		Description: "Show who is going to be kicked next and cancel it (admins only)",
+	},
+	{
+		Text:        "usage",
+		Description: "Show how often each command is used (admins only)",
+	},
+	{
+		Text:        "log",
+		Description: "Show the last bot decisions (admins only)",
+	},
+	{
+		Text:        "about",
+		Description: "Show what this bot is and which rules it enforces",
+	},
+}
+
 type Watcher struct {
-	bot      *telebot.Bot
-	chat     *telebot.Chat
-	store    *mgo.Collection
-	duration time.Duration
+	bot       *telebot.Bot
+	chat      *telebot.Chat
+	owner     int64
+	store     *mgo.Collection
+	audit     *mgo.Collection
+	history   *mgo.Collection
+	snapshots *mgo.Collection
+	cycles    *mgo.Collection
+	locks     *mgo.Collection
+
+	defaultPolicy Policy
+
+	settingsStore *mgo.Collection
+	settings      Settings
+	mutex         sync.Mutex
+
+	initialCredit time.Duration
+	kicksPerHour  int
+	clockJumpMax  time.Duration
+
+	joinKicks  int
+	joinAction string
+
+	whenCooldown *Cooldown
+	whenCache    *RenderCache
+
+	pending  *ActivityQueue
+	breaker  *CircuitBreaker
+	usage    *Usage
+	backuper *Backuper
+
+	digestInterval time.Duration
+
+	rejoinGrace time.Duration
+	rejoinRules string
+
+	exempters []Exempter
+
+	auditRetention    time.Duration
+	historyRetention  time.Duration
+	snapshotRetention time.Duration
+
+	rolloutSince   time.Time
+	rolloutPercent int
+
+	vouchThreshold int
+	vouchWindow    time.Duration
+
+	instance string
+	lockTTL  time.Duration
+
+	stopping  chan struct{}
+	enforcing sync.WaitGroup
+	running   int32
+
+	finish        chan chan struct{}
+	handling      sync.WaitGroup
+	lastProcessed int64
+
+	renameAction    string
+	serviceActivity map[string]bool
+
+	started     time.Time
+	nextEnforce time.Time
+	aboutURL    string
+
+	metricsListen string
+
+	settingsReload time.Duration
+
+	standby bool
+
+	unbannableNotified time.Time
+
+	pardonInvite bool
+	rotateInvite bool
+	whenBucketed bool
 }
 
 func main() {
@@ -55,14 +244,112 @@ func main() {
 	var (
 		telegramToken = stringEnv("TELEGRAM_TOKEN")
 		telegramChat  = intEnv("TELEGRAM_CHAT")
-		duration      = durationEnv("DURATION")
+		owner         = optionalIntEnv("OWNER_ID", 0)
+		preset        = optionalStringEnv("PRESET", "")
 
 		mongoURI = stringEnv("MONGODB_URI")
+
+		pardonInvite = boolEnv("PARDON_INVITE")
+		rotateInvite = boolEnv("ROTATE_INVITE")
+		whenBucketed = boolEnv("WHEN_BUCKETED")
+
+		pollerSilence = optionalDurationEnv("POLLER_SILENCE", 15*time.Minute)
+		initialCredit = optionalDurationEnv("INITIAL_CREDIT", 0)
+		kicksPerHour  = optionalIntEnv("KICKS_PER_HOUR", 0)
+		clockJumpMax  = optionalDurationEnv("CLOCK_JUMP_MAX", 5*time.Minute)
+
+		joinKicks  = optionalIntEnv("JOIN_REQUEST_KICKS", 0)
+		joinAction = optionalStringEnv("JOIN_REQUEST_ACTION", joinActionAdmins)
+
+		whenCooldown = optionalDurationEnv("WHEN_COOLDOWN", 30*time.Second)
+		whenCacheTTL = optionalDurationEnv("WHEN_CACHE_TTL", time.Minute)
+
+		queueSize = optionalIntEnv("ACTIVITY_QUEUE_SIZE", 10000)
+
+		metricsListen = optionalStringEnv("METRICS_LISTEN", "")
+
+		backupBucket = optionalStringEnv("BACKUP_S3_BUCKET", "")
+
+		aboutURL = optionalStringEnv("ABOUT_URL", "")
+
+		renameAction = optionalStringEnv("RENAME_ACTION", "")
+
+		serviceActivity = parseServiceActivity(listEnv("SERVICE_ACTIVITY"))
+
+		digestInterval = optionalDurationEnv("DIGEST_INTERVAL", 0)
+
+		rejoinGrace = optionalDurationEnv("REJOIN_GRACE", 0)
+		rejoinRules = optionalStringEnv("REJOIN_RULES", "")
+
+		exemptProviders = listEnv("EXEMPT_PROVIDERS")
+		exemptURL       = optionalStringEnv("EXEMPT_URL", "")
+		exemptCacheTTL  = optionalDurationEnv("EXEMPT_CACHE_TTL", time.Hour)
+
+		auditRetention   = optionalDurationEnv("AUDIT_RETENTION", 0)
+		historyRetention = optionalDurationEnv("HISTORY_RETENTION", 0)
+
+		snapshotRetention = optionalDurationEnv("SNAPSHOT_RETENTION", 90*day)
+
+		rolloutSince   = optionalStringEnv("ROLLOUT_SINCE", "")
+		rolloutPercent = optionalIntEnv("ROLLOUT_PERCENT", 0)
+
+		breakerThreshold = optionalIntEnv("BREAKER_THRESHOLD", 10)
+		breakerWindow    = optionalDurationEnv("BREAKER_WINDOW", 10*time.Minute)
+		breakerCooldown  = optionalDurationEnv("BREAKER_COOLDOWN", time.Hour)
+
+		vouchThreshold = optionalIntEnv("VOUCH_THRESHOLD", 0)
+		vouchWindow    = optionalDurationEnv("VOUCH_WINDOW", 24*time.Hour)
+
+		lockTTL = optionalDurationEnv("LEADER_LOCK_TTL", 0)
+
+		settingsReload = optionalDurationEnv("SETTINGS_RELOAD", time.Minute)
 	)
 
+	if renameAction != "" && renameAction != renameFlag && renameAction != renameReset {
+		log.Fatalf(
+			nil,
+			"RENAME_ACTION must be %q or %q",
+			renameFlag, renameReset,
+		)
+	}
+
+	if joinAction != joinActionDecline && joinAction != joinActionAdmins {
+		log.Fatalf(
+			nil,
+			"JOIN_REQUEST_ACTION must be %q or %q",
+			joinActionDecline, joinActionAdmins,
+		)
+	}
+
+	if boolEnv("LOG_REDACT") {
+		logRedactKey = []byte(telegramToken)
+	}
+
+	policy, ok := presets[preset]
+	if preset != "" && !ok {
+		log.Fatalf(nil, "unknown preset %q, available: %v", preset, presetNames())
+	}
+
+	if preset == "" {
+		policy = Policy{
+			Duration:   durationEnv("DURATION"),
+			WarnBefore: optionalDurationEnv("WARN_BEFORE", 0),
+			Action:     optionalStringEnv("ACTION", actionKick),
+		}
+	}
+
+	if policy.Action != actionKick && policy.Action != actionMute {
+		log.Fatalf(nil, "ACTION must be %q or %q", actionKick, actionMute)
+	}
+
+	longPoller := &telebot.LongPoller{Timeout: 10 * time.Second}
+	watchdog := NewWatchdogPoller(longPoller, pollerSilence)
+
 	bot, err := telebot.NewBot(telebot.Settings{
-		Token:  telegramToken,
-		Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
+		Token:       telegramToken,
+		OnError:     onError,
+		Poller:      watchdog,
+		Synchronous: true,
 	})
 	if err != nil {
 		log.Fatalf(err, "telegram bot init")
@@ -74,25 +361,315 @@ func main() {
 	}
 
 	store := mongoSession.DB("").C("chat")
+	audit := mongoSession.DB("").C("audit")
+	history := mongoSession.DB("").C("history")
+	settingsStore := mongoSession.DB("").C("settings")
+	snapshots := mongoSession.DB("").C("snapshots")
+	cycles := mongoSession.DB("").C("cycles")
+	locks := mongoSession.DB("").C("locks")
 
 	watcher := &Watcher{
-		bot:      bot,
-		chat:     &telebot.Chat{ID: int64(telegramChat)},
-		store:    store,
-		duration: duration,
+		bot:     bot,
+		chat:    &telebot.Chat{ID: int64(telegramChat)},
+		owner:   int64(owner),
+		store:   store,
+		audit:   audit,
+		history: history,
+
+		snapshots: snapshots,
+		cycles:    cycles,
+		locks:     locks,
+
+		defaultPolicy: policy,
+
+		settingsStore: settingsStore,
+
+		initialCredit: initialCredit,
+		kicksPerHour:  kicksPerHour,
+		clockJumpMax:  clockJumpMax,
+
+		joinKicks:  joinKicks,
+		joinAction: joinAction,
+
+		whenCooldown: NewCooldown(whenCooldown),
+		whenCache:    NewRenderCache(whenCacheTTL),
+
+		pending: NewActivityQueue(queueSize),
+		breaker: NewCircuitBreaker(
+			breakerThreshold,
+			breakerWindow,
+			breakerCooldown,
+		),
+		usage: NewUsage(),
+
+		stopping: make(chan struct{}),
+		finish:   make(chan chan struct{}),
+
+		started:  time.Now(),
+		aboutURL: aboutURL,
+
+		metricsListen: metricsListen,
+
+		settingsReload: settingsReload,
+
+		digestInterval: digestInterval,
+
+		rejoinGrace: rejoinGrace,
+		rejoinRules: rejoinRules,
+
+		auditRetention:    auditRetention,
+		historyRetention:  historyRetention,
+		snapshotRetention: snapshotRetention,
+
+		rolloutPercent: rolloutPercent,
+
+		vouchThreshold: vouchThreshold,
+		vouchWindow:    vouchWindow,
+
+		instance: instanceID(),
+		lockTTL:  lockTTL,
+
+		renameAction:    renameAction,
+		serviceActivity: serviceActivity,
+
+		pardonInvite: pardonInvite,
+		rotateInvite: rotateInvite,
+		whenBucketed: whenBucketed,
+	}
+
+	if rolloutSince != "" {
+		watcher.rolloutSince, err = parseRolloutSince(rolloutSince)
+		if err != nil {
+			log.Fatalf(err, "parse ROLLOUT_SINCE: %s", rolloutSince)
+		}
+	}
+
+	if rolloutPercent < 0 || rolloutPercent > 100 {
+		log.Fatalf(nil, "ROLLOUT_PERCENT must be between 0 and 100")
+	}
+
+	for _, name := range exemptProviders {
+		exempter, err := watcher.newExempter(name, exemptURL, exemptCacheTTL)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		watcher.exempters = append(watcher.exempters, exempter)
+	}
+
+	if backupBucket != "" {
+		client, err := minio.New(
+			stringEnv("BACKUP_S3_ENDPOINT"),
+			&minio.Options{
+				Creds: credentials.NewStaticV4(
+					stringEnv("BACKUP_S3_ACCESS_KEY"),
+					stringEnv("BACKUP_S3_SECRET_KEY"),
+					"",
+				),
+				Secure: !boolEnv("BACKUP_S3_INSECURE"),
+			},
+		)
+		if err != nil {
+			log.Fatalf(err, "s3 client init")
+		}
+
+		watcher.backuper = &Backuper{
+			client:    client,
+			bucket:    backupBucket,
+			prefix:    optionalStringEnv("BACKUP_S3_PREFIX", "telekick/"),
+			interval:  optionalDurationEnv("BACKUP_INTERVAL", 24*time.Hour),
+			retention: optionalDurationEnv("BACKUP_RETENTION", 0),
+		}
+	}
+
+	err = watcher.loadSettings()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = watcher.ensureCycleIndex()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	watcher.standby, _ = args["--standby"].(bool)
+
+	if mode, _ := args["--features"].(bool); mode {
+		fmt.Println(watcher.listFeatures())
+		return
 	}
 
 	if mode, _ := args["--stats"].(bool); mode {
-		entries, err := watcher.listTimestamps()
+		entries, err := watcher.listTimestamps(false, true, watcher.chatLanguage())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		summary, err := watcher.summary()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Println(entries)
+		fmt.Println()
+		fmt.Print(summary)
+		return
+	}
+
+	if mode, _ := args["restore"].(bool); mode {
+		if watcher.backuper == nil {
+			log.Fatalf(nil, "no env %q specified", "BACKUP_S3_BUCKET")
+		}
+
+		err := watcher.restore(args["<backup>"].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if mode, _ := args["export"].(bool); mode {
+		manifest, err := watcher.exportConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		os.Stdout.Write(manifest)
+
+		return
+	}
+
+	if mode, _ := args["apply"].(bool); mode {
+		err := watcher.applyConfig(args["<manifest>"].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if mode, _ := args["query"].(bool); mode {
+		users, err := watcher.query(args["<expression>"].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		format, ok := args["--format"].(string)
+		if !ok {
+			format = args["--report"].(string)
+		}
+
+		report, err := renderReport(users, format)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		os.Stdout.Write(report)
+
+		return
+	}
+
+	if mode, _ := args["diff"].(bool); mode {
+		since, err := parseDuration(args["--since"].(string))
+		if err != nil {
+			log.Fatalf(err, "parse --since")
+		}
+
+		churn, err := watcher.diff(since)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = json.NewEncoder(os.Stdout).Encode(churn)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if mode, _ := args["broadcast"].(bool); mode {
+		onlyAtRisk, _ := args["--only-at-risk"].(bool)
+
+		delivery, err := watcher.broadcast(args["--file"].(string), onlyAtRisk)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = json.NewEncoder(os.Stdout).Encode(delivery)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		fmt.Print(entries)
 		return
 	}
 
+	if mode, _ := args["run-once"].(bool); mode {
+		dryRun, _ := args["--dry-run"].(bool)
+
+		summary, err := watcher.enforce(dryRun)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if reportTo, ok := args["--report-to"].(string); ok && dryRun {
+			chat, err := strconv.ParseInt(reportTo, 10, 64)
+			if err != nil {
+				log.Fatalf(err, "parse --report-to: %s", reportTo)
+			}
+
+			err = watcher.sendReport(
+				&telebot.Chat{ID: chat},
+				summary,
+				args["--report"].(string),
+			)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		err = json.NewEncoder(os.Stdout).Encode(summary)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if metricsListen != "" {
+		go serveMetrics(metricsListen)
+	}
+
+	if watcher.lockTTL > 0 {
+		lock, err := watcher.acquireLeader()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		longPoller.LastUpdateID = lock.Offset
+		watcher.lastProcessed = int64(lock.Offset)
+	}
+
 	go watcher.Record()
+	go watcher.ReplayActivity()
+
+	if watcher.settingsReload > 0 {
+		go watcher.ReloadSettings()
+	}
+
+	if watcher.backuper != nil && !watcher.standby {
+		go watcher.Backup()
+	}
+
+	if watcher.digestInterval > 0 && !watcher.standby {
+		go watcher.Digest()
+	}
+
+	if (auditRetention > 0 || historyRetention > 0 || snapshotRetention > 0) &&
+		!watcher.standby {
+		go watcher.Prune()
+	}
 	go watcher.WatchKick()
 
 	log.Infof(nil, "telekick started")
@@ -106,11 +683,44 @@ func main() {
 		os.Kill,
 	)
 	<-signals
+
+	log.Infof(nil, "stopping, waiting for updates and enforcement to finish")
+
+	close(watcher.stopping)
+
+	watcher.handoff(watchdog)
+	watcher.enforcing.Wait()
+	watcher.replayActivity()
+
+	if watcher.lockTTL > 0 {
+		err := watcher.releaseLeader(int(atomic.LoadInt64(&watcher.lastProcessed)))
+		if err != nil {
+			log.Error(err)
+		}
+	}
 }
 
-func (watcher *Watcher) listTimestamps() (string, error) {
+func (watcher *Watcher) isStopping() bool {
+	select {
+	case <-watcher.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+func (watcher *Watcher) listTimestamps(
+	bucketed bool,
+	hidden bool,
+	language string,
+) (string, error) {
+	query := bson.M{}
+	if !hidden {
+		query["hidden"] = bson.M{"$ne": true}
+	}
+
 	var users []User
-	err := watcher.store.Find(bson.M{}).Sort("last_message").All(&users)
+	err := watcher.store.Find(query).Sort("last_message").All(&users)
 	if err != nil {
 		return "", err
 	}
@@ -119,177 +729,336 @@ func (watcher *Watcher) listTimestamps() (string, error) {
 	for _, user := range users {
 		chat, err := watcher.bot.ChatByID(user.UserID)
 		if err != nil {
-			log.Errorf(err, "chat by id: %v", user.UserID)
+			log.Errorf(err, "chat by id: %v", loggedUser(user.UserID))
 			continue
 		}
 
-		timestamp := time.Unix(user.LastMessage, 0)
+		idle := time.Now().Sub(time.Unix(user.LastMessage, 0))
+
+		since := humanAgo(idle, language)
+		if bucketed {
+			since = idleBucket(idle)
+		}
+
+		if hidden && user.Flagged {
+			since += " (renamed)"
+		}
 
 		entries = append(
 			entries,
 			"@"+chat.Username+" "+
 				chat.FirstName+" "+
 				chat.LastName+" "+
-				time.Now().Sub(timestamp).String(),
+				since,
 		)
 	}
 
 	return strings.Join(entries, "\n"), nil
 }
 
-func (watcher *Watcher) handle(update telebot.Update) error {
-	if update.Message == nil {
-		return nil
+func (watcher *Watcher) cachedTimestamps(
+	bucketed bool,
+	hidden bool,
+	language string,
+) (string, error) {
+	return watcher.whenCache.Get(
+		fmt.Sprintf("%t/%t/%s", bucketed, hidden, language),
+		func() (string, error) {
+			return watcher.listTimestamps(bucketed, hidden, language)
+		},
+	)
+}
+
+func (watcher *Watcher) handleWhen(context telebot.Context) error {
+	admin, err := watcher.isAdmin(context.Sender())
+	if err != nil {
+		log.Error(err)
 	}
 
-	if update.Message.Text == "/when" || update.Message.Text == "q" {
-		entries, err := watcher.listTimestamps()
+	if watcher.whenBucketed && !context.Message().Private() {
+		entries, err := watcher.cachedTimestamps(true, false, watcher.chatLanguage())
 		if err != nil {
 			return err
 		}
 
-		_, err = watcher.bot.Send(update.Message.Sender, entries)
+		return context.Reply(entries)
+	}
+
+	entries, err := watcher.cachedTimestamps(
+		false,
+		admin,
+		watcher.userLanguage(context.Sender().LanguageCode),
+	)
+	if err != nil {
 		return err
 	}
 
-	if update.Message.UserLeft != nil {
-		log.Infof(nil, "remove user: %v now: %v", update.Message.UserLeft.ID)
+	_, err = watcher.sendPrivate(context.Sender(), entries)
+	if err != nil && isForbidden(err) && !context.Message().Private() {
+		markup := &telebot.ReplyMarkup{}
+		markup.Inline(markup.Row(markup.URL(
+			"Open private chat",
+			"https://t.me/"+watcher.bot.Me.Username+"?start="+startWhen,
+		)))
 
-		err := watcher.store.Remove(
-			bson.M{"user_id": update.Message.UserLeft.ID},
+		return context.Reply(
+			"I can't message you privately yet. "+
+				"Start a chat with me and I'll send the list right away.",
+			markup,
 		)
-		if err != nil {
-			return karma.Format(err, "remove user")
-		}
+	}
 
+	return err
+}
+
+func (watcher *Watcher) handleStart(context telebot.Context) error {
+	if !context.Message().Private() || context.Message().Payload != startWhen {
 		return nil
 	}
 
-	if update.Message.UserJoined != nil {
-		return watcher.updateLastMessage(update.Message.UserJoined.ID)
+	return watcher.handleWhen(context)
+}
+
+func (watcher *Watcher) handleUserLeft(context telebot.Context) error {
+	user := context.Message().UserLeft
+
+	log.Infof(nil, "remove user: %v", loggedUser(user.ID))
+
+	err := watcher.store.Remove(bson.M{"user_id": user.ID})
+	if err != nil {
+		return karma.Format(err, "remove user")
 	}
 
-	if update.Message.Sender == nil {
-		return nil
+	sender := context.Sender()
+	if sender != nil && sender.ID != user.ID && sender.ID != watcher.bot.Me.ID {
+		return watcher.record(AuditRecord{
+			UserID:   user.ID,
+			Username: user.Username,
+			Action:   auditRemove,
+			Actor:    sender.ID,
+		})
 	}
 
-	return watcher.updateLastMessage(update.Message.Sender.ID)
+	return nil
 }
 
-func (watcher *Watcher) updateLastMessage(user int64) error {
-	now := time.Now().Unix()
+func (watcher *Watcher) handleUserJoined(context telebot.Context) error {
+	if watcher.isPaused() {
+		return nil
+	}
 
-	log.Infof(nil, "update user: %v now: %v", user, now)
+	user := context.Message().UserJoined
 
-	_, err := watcher.store.Upsert(
-		bson.M{"user_id": user},
-		bson.M{
-			"$set": bson.M{
-				"user_id":      user,
-				"last_message": now,
-			},
-		},
+	err := watcher.updateLastMessage(user)
+	if err != nil {
+		return err
+	}
+
+	err = watcher.store.Update(
+		bson.M{"user_id": user.ID},
+		bson.M{"$set": bson.M{"joined_at": time.Now().Unix()}},
 	)
 	if err != nil {
-		return karma.Format(err, "update user")
+		return karma.Format(err, "record join: %v", loggedUser(user.ID))
+	}
+
+	if watcher.rejoinGrace > 0 && !watcher.standby {
+		return watcher.handleRejoin(context)
 	}
 
 	return nil
 }
 
-func (watcher *Watcher) Record() {
-	updates := make(chan telebot.Update)
-	stop := make(chan struct{})
+func (watcher *Watcher) handleActivity(context telebot.Context) error {
+	if watcher.isPaused() || context.Sender() == nil {
+		return nil
+	}
 
-	go watcher.bot.Poller.Poll(watcher.bot, updates, stop)
+	rule := watcher.topicRule(context.Message())
+	if rule == topicIgnore {
+		return nil
+	}
 
-	err := watcher.bot.SetCommands(
-		telebot.Command{
-			Text:        "/when",
-			Description: "Show the list of users and number of hours since their last message",
-		},
-	)
+	if watcher.renameAction != "" {
+		err := watcher.detectRename(context.Sender())
+		if err != nil {
+			log.Errorf(err, "detect rename")
+		}
+	}
+
+	err := watcher.updateLastMessage(context.Sender())
 	if err != nil {
-		log.Fatalf(err, "set commands")
+		return err
 	}
 
-	for update := range updates {
-		watcher.handle(update)
+	err = watcher.clearWarning(context.Sender())
+	if err != nil {
+		log.Error(err)
 	}
 
-	log.Infof(nil, "telekick started")
+	messages := 1
+	if rule == topicDouble {
+		messages = 2
+	}
+
+	return watcher.countMessages(context.Sender().ID, messages)
 }
 
-func (watcher *Watcher) WatchKick() {
-	interval := time.Hour
+func (watcher *Watcher) updateLastMessage(user *telebot.User) error {
+	now := time.Now().Unix()
 
-	for {
-		since, err := watcher.store.Find(bson.M{
-			"last_message": bson.M{
-				"$gt": time.Now().Add(watcher.duration * -1).Unix(),
-			},
-		}).Count()
+	if watcher.initialCredit > 0 {
+		known, err := watcher.store.Find(bson.M{"user_id": user.ID}).Count()
 		if err != nil {
-			log.Fatalf(err, "find messages")
+			watcher.enqueueActivity(Activity{
+				UserID:    user.ID,
+				Username:  user.Username,
+				Timestamp: now,
+			})
+
+			return karma.Format(err, "find user: %v, queued for replay", loggedUser(user.ID))
 		}
 
-		if since == 0 {
-			log.Infof(nil, "no messages since %v", watcher.duration)
-			time.Sleep(interval)
-			continue
+		if known == 0 {
+			now += int64(watcher.initialCredit.Seconds())
 		}
+	}
 
-		var users []User
-		err = watcher.store.Find(bson.M{
-			"last_message": bson.M{
-				"$lt": time.Now().Add(watcher.duration * -1).Unix(),
-			},
-		}).All(&users)
+	log.Infof(nil, "update user: %v now: %v", loggedUser(user.ID), now)
+
+	set := bson.M{
+		"user_id":      user.ID,
+		"last_message": now,
+		"unbannable":   false,
+		"muted":        false,
+	}
+	unset := bson.M{}
+
+	names := map[string]string{
+		"username":   user.Username,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+	}
+	for field, value := range names {
+		if value != "" {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
 
-		for _, user := range users {
-			log.Infof(nil, "kick %v", user.UserID)
+	if user.LanguageCode != "" {
+		set["language"] = user.LanguageCode
+	}
 
-			err = watcher.ban(user.UserID)
+	update := bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{"first_seen": time.Now().Unix()},
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	_, err := watcher.store.Upsert(bson.M{"user_id": user.ID}, update)
+	if err != nil {
+		watcher.enqueueActivity(Activity{
+			UserID:    user.ID,
+			Username:  user.Username,
+			Timestamp: now,
+		})
+
+		return karma.Format(err, "update user, queued for replay")
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) Record() {
+	err := watcher.bot.SetCommands(commands)
+	if err != nil {
+		log.Fatalf(err, "set commands")
+	}
+
+	watcher.route()
+	watcher.dispatch()
+}
+
+func (watcher *Watcher) WatchKick() {
+	var previous time.Time
+
+	for {
+		now := time.Now()
+
+		watcher.setNextEnforce(now.Add(enforceInterval))
+
+		if !previous.IsZero() {
+			jump := clockJump(previous, now)
+			if jump > watcher.clockJumpMax {
+				log.Warningf(
+					nil,
+					"clock jumped by %v since previous cycle, skip enforcement",
+					jump,
+				)
+
+				metricClockJumps.Inc()
+
+				previous = now
+				time.Sleep(enforceInterval)
+				continue
+			}
+		}
+
+		previous = now
+
+		stats, err := watcher.aggregateStats()
+		if err != nil {
+			log.Errorf(err, "aggregate stats")
+		} else if !watcher.standby {
+			err = watcher.saveStats(stats)
 			if err != nil {
-				log.Errorf(err, "ban %v", user.UserID)
+				log.Error(err)
 			}
 		}
 
-		time.Sleep(interval)
+		if !watcher.standby {
+			err = watcher.saveSnapshot()
+			if err != nil {
+				log.Error(err)
+			}
+
+			err = watcher.expireMessages()
+			if err != nil {
+				log.Error(err)
+			}
+		}
+
+		_, err = watcher.enforce(false)
+		if err != nil {
+			log.Errorf(err, "enforce")
+		}
+
+		time.Sleep(enforceInterval)
 	}
 }
 
 func (watcher *Watcher) ban(user int64) error {
 	params := map[string]string{
-		"chat_id":    watcher.chat.Recipient(),
+		"chat_id":    watcher.getChat().Recipient(),
 		"user_id":    strconv.FormatInt(user, 10),
 		"until_date": strconv.FormatInt(telebot.Forever(), 10),
 	}
 
-	data, banErr := watcher.bot.Raw("banChatMember", params)
-	if banErr != nil {
-		migrations := struct {
-			Parameters struct {
-				MigrateToChatID int64 `json:"migrate_to_chat_id"`
-			} `json:"parameters"`
-		}{}
-
-		err := json.Unmarshal(data, &migrations)
-		if err != nil {
-			return banErr
+	_, err := watcher.bot.Raw("banChatMember", params)
+	if group, ok := err.(telebot.GroupError); ok {
+		migrateErr := watcher.migrate(group.MigratedTo)
+		if migrateErr != nil {
+			log.Errorf(migrateErr, "migrate chat")
 		}
 
-		if migrations.Parameters.MigrateToChatID != 0 {
-			params := map[string]string{
-				"chat_id":    fmt.Sprint(migrations.Parameters.MigrateToChatID),
-				"user_id":    strconv.FormatInt(user, 10),
-				"until_date": strconv.FormatInt(telebot.Forever(), 10),
-			}
+		params["chat_id"] = fmt.Sprint(group.MigratedTo)
 
-			_, banErr = watcher.bot.Raw("banChatMember", params)
-			return banErr
-		}
+		_, err = watcher.bot.Raw("banChatMember", params)
 	}
 
-	return nil
+	return classifyBanError(user, err)
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	"gopkg.in/yaml.v2"
+)
+
+type Manifest struct {
+	Chats []Settings `yaml:"chats"`
+}
+
+func (watcher *Watcher) exportConfig() ([]byte, error) {
+	var manifest Manifest
+	err := watcher.settingsStore.Find(nil).Sort("chat_id").All(&manifest.Chats)
+	if err != nil {
+		return nil, karma.Format(err, "find settings")
+	}
+
+	return yaml.Marshal(manifest)
+}
+
+func validateSettings(settings Settings) error {
+	if settings.ChatID == 0 {
+		return karma.Format(nil, "chat_id is required")
+	}
+
+	if _, ok := presets[settings.Preset]; settings.Preset != "" && !ok {
+		return karma.Format(
+			nil,
+			"unknown preset %q, available: %v",
+			settings.Preset, presetNames(),
+		)
+	}
+
+	if settings.Language != "" && supportedLanguage(settings.Language) == "" {
+		return karma.Format(
+			nil,
+			"unsupported language %q, available: %v",
+			settings.Language, languages,
+		)
+	}
+
+	switch settings.Unbannable {
+	case "", unbannableRetry, unbannableSkip, unbannableNotify:
+	default:
+		return karma.Format(
+			nil,
+			"unknown unbannable action %q, available: %v",
+			settings.Unbannable, unbannableActions,
+		)
+	}
+
+	if settings.Duration != "" {
+		_, err := parsePolicyDuration(settings.Duration)
+		if err != nil {
+			return karma.Format(err, "invalid duration %q", settings.Duration)
+		}
+	}
+
+	for topic, rule := range settings.Topics {
+		if rule != topicNormal && rule != topicIgnore && rule != topicDouble {
+			return karma.Format(nil, "unknown rule for topic %s: %q", topic, rule)
+		}
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) applyConfig(path string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return karma.Format(err, "read manifest: %s", path)
+	}
+
+	var manifest Manifest
+	err = yaml.UnmarshalStrict(contents, &manifest)
+	if err != nil {
+		return karma.Format(err, "parse manifest: %s", path)
+	}
+
+	for _, settings := range manifest.Chats {
+		err := validateSettings(settings)
+		if err != nil {
+			return karma.Format(err, "invalid settings for chat %v", settings.ChatID)
+		}
+	}
+
+	for _, settings := range manifest.Chats {
+		var existing Settings
+		err := watcher.settingsStore.Find(
+			bson.M{"chat_id": settings.ChatID},
+		).One(&existing)
+		if err != nil && err != mgo.ErrNotFound {
+			return karma.Format(err, "find settings: %v", settings.ChatID)
+		}
+
+		_, err = watcher.settingsStore.Upsert(
+			bson.M{"chat_id": settings.ChatID},
+			settings,
+		)
+		if err != nil {
+			return karma.Format(err, "save settings: %v", settings.ChatID)
+		}
+
+		watcher.recordSettingChanges(
+			0,
+			fmt.Sprintf("chat %v, ", settings.ChatID),
+			diffSettings(existing, settings),
+		)
+
+		log.Infof(
+			nil,
+			"applied settings for chat %v, running instances reload them within SETTINGS_RELOAD",
+			settings.ChatID,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+)
+
+const (
+	messageWindow    = 30 * day
+	messageDayLayout = "20060102"
+)
+
+func messageDay(moment time.Time) string {
+	return moment.UTC().Format(messageDayLayout)
+}
+
+func (watcher *Watcher) countMessages(user int64, messages int) error {
+	err := watcher.store.Update(
+		bson.M{"user_id": user},
+		bson.M{"$inc": bson.M{
+			"messages":                               messages,
+			"messages_30d":                           messages,
+			"message_days." + messageDay(time.Now()): messages,
+		}},
+	)
+	if err != nil {
+		return karma.Format(err, "count message")
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) expireMessages() error {
+	oldest := messageDay(time.Now().Add(-messageWindow))
+
+	iter := watcher.store.Find(bson.M{"messages_30d": bson.M{"$gt": 0}}).
+		Select(bson.M{"user_id": 1, "message_days": 1}).
+		Batch(enforceBatch).
+		Iter()
+
+	var user User
+	for iter.Next(&user) {
+		unset := bson.M{}
+		expired := int64(0)
+		for day, count := range user.MessageDays {
+			if day < oldest {
+				unset["message_days."+day] = ""
+				expired += count
+			}
+		}
+
+		user.MessageDays = nil
+
+		if len(unset) == 0 {
+			continue
+		}
+
+		err := watcher.store.Update(
+			bson.M{"user_id": user.UserID},
+			bson.M{
+				"$unset": unset,
+				"$inc":   bson.M{"messages_30d": -expired},
+			},
+		)
+		if err != nil {
+			iter.Close()
+			return karma.Format(err, "expire messages: %v", loggedUser(user.UserID))
+		}
+	}
+
+	err := iter.Close()
+	if err != nil {
+		return karma.Format(err, "find users with recent messages")
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/reconquest/pkg/log"
+)
+
+var (
+	metricClockJumps = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "telekick_clock_jumps_total",
+		Help: "Number of enforcement cycles skipped because of a clock jump.",
+	})
+
+	metricWarnings = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "telekick_warnings_total",
+		Help: "Number of inactivity warnings sent.",
+	})
+
+	metricWarningsSaved = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "telekick_warnings_saved_total",
+		Help: "Number of warned users who became active before being kicked.",
+	})
+)
+
+var idleBuckets = []float64{
+	day.Seconds(),
+	3 * day.Seconds(),
+	week.Seconds(),
+	2 * week.Seconds(),
+	30 * day.Seconds(),
+	60 * day.Seconds(),
+	90 * day.Seconds(),
+	180 * day.Seconds(),
+}
+
+type IdleCollector struct {
+	desc    *prometheus.Desc
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64
+	mutex   sync.Mutex
+}
+
+var metricIdle = &IdleCollector{
+	desc: prometheus.NewDesc(
+		"telekick_idle_seconds",
+		"Distribution of member idle times at the last enforcement cycle.",
+		nil, nil,
+	),
+	buckets: map[float64]uint64{},
+}
+
+func init() {
+	prometheus.MustRegister(metricIdle)
+}
+
+func (collector *IdleCollector) Update(
+	count uint64,
+	sum float64,
+	buckets map[float64]uint64,
+) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	collector.count = count
+	collector.sum = sum
+	collector.buckets = buckets
+}
+
+func (collector *IdleCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- collector.desc
+}
+
+func (collector *IdleCollector) Collect(metrics chan<- prometheus.Metric) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	metrics <- prometheus.MustNewConstHistogram(
+		collector.desc,
+		collector.count,
+		collector.sum,
+		collector.buckets,
+	)
+}
+
+func serveMetrics(address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	log.Infof(nil, "serving metrics on %s", address)
+
+	err := http.ListenAndServe(address, mux)
+	if err != nil {
+		log.Fatalf(err, "serve metrics")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+func (watcher *Watcher) handleMigration(context telebot.Context) error {
+	from, to := context.Migration()
+	if from != watcher.getChat().ID {
+		return nil
+	}
+
+	return watcher.migrate(to)
+}
+
+func (watcher *Watcher) migrate(to int64) error {
+	from := watcher.getChat().ID
+	if from == to {
+		return nil
+	}
+
+	log.Warningf(nil, "chat %v migrated to %v", from, to)
+
+	if watcher.standby {
+		watcher.setChat(to)
+		return nil
+	}
+
+	settings := watcher.getSettings()
+
+	_, err := watcher.settingsStore.Upsert(
+		bson.M{"chat_id": from},
+		bson.M{"$set": bson.M{"migrated_to": to}},
+	)
+	if err != nil {
+		return karma.Format(err, "mark chat %v as migrated", from)
+	}
+
+	watcher.setChat(to)
+
+	settings.MigratedTo = 0
+
+	err = watcher.saveSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	err = watcher.notifyAdmins(
+		fmt.Sprintf(
+			"This chat was upgraded to a supergroup, telekick follows it now. "+
+				"Please update TELEGRAM_CHAT from %v to %v.",
+			from, to,
+		),
+	)
+	if err != nil {
+		log.Errorf(err, "notify about chat migration")
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+func (watcher *Watcher) isAdmin(user *telebot.User) (bool, error) {
+	member, err := watcher.bot.ChatMemberOf(watcher.getChat(), user)
+	if err != nil {
+		return false, karma.Format(err, "get chat member: %v", loggedUser(user.ID))
+	}
+
+	return member.Role == telebot.Administrator || member.Role == telebot.Creator, nil
+}
+
+func (watcher *Watcher) handlePardon(context telebot.Context) error {
+	args := context.Args()
+	if len(args) != 1 {
+		return context.Reply("Usage: /pardon @user")
+	}
+
+	target, err := watcher.findPardonTarget(args[0])
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return context.Reply("No kicked or muted user " + args[0] + " found.")
+		}
+
+		return karma.Format(err, "find kicked or muted user: %s", loggedName(args[0]))
+	}
+
+	err = watcher.pardon(target.UserID, target.Username, context.Sender().ID)
+	if err != nil {
+		return err
+	}
+
+	return context.Reply("Pardoned " + args[0] + ".")
+}
+
+func (watcher *Watcher) findPardonTarget(target string) (*AuditRecord, error) {
+	if id, err := strconv.ParseInt(target, 10, 64); err == nil {
+		return &AuditRecord{UserID: id}, nil
+	}
+
+	return watcher.findEnforced(strings.TrimPrefix(target, "@"))
+}
+
+func (watcher *Watcher) pardon(userID int64, username string, actor int64) error {
+	user := &telebot.User{ID: userID, Username: username}
+
+	log.Infof(nil, "pardon user: %v by: %v", loggedUser(userID), loggedUser(actor))
+
+	muted, err := watcher.audit.Find(
+		bson.M{"user_id": userID, "action": auditMute, "active": true},
+	).Count()
+	if err != nil {
+		return karma.Format(err, "find mutes: %v", loggedUser(userID))
+	}
+
+	if muted > 0 {
+		err = watcher.liftMute(userID)
+	} else {
+		err = watcher.liftKick(user)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = watcher.record(AuditRecord{
+		UserID:   userID,
+		Username: username,
+		Action:   auditPardon,
+		Actor:    actor,
+	})
+	if err != nil {
+		return err
+	}
+
+	if muted == 0 && watcher.pardonInvite {
+		err = watcher.sendInvite(user)
+		if err != nil {
+			log.Errorf(err, "send invite to pardoned user: %v", loggedUser(userID))
+		}
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) liftKick(user *telebot.User) error {
+	err := watcher.bot.Unban(watcher.getChat(), user, true)
+	if err != nil {
+		return karma.Format(err, "unban user: %v", loggedUser(user.ID))
+	}
+
+	_, err = watcher.audit.UpdateAll(
+		bson.M{"user_id": user.ID, "action": auditKick, "active": true},
+		bson.M{"$set": bson.M{"active": false}},
+	)
+	if err != nil {
+		return karma.Format(err, "clear kicked status: %v", loggedUser(user.ID))
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) liftMute(user int64) error {
+	err := watcher.unmute(user)
+	if err != nil {
+		return karma.Format(err, "unmute user: %v", loggedUser(user))
+	}
+
+	_, err = watcher.audit.UpdateAll(
+		bson.M{"user_id": user, "action": auditMute, "active": true},
+		bson.M{"$set": bson.M{"active": false}},
+	)
+	if err != nil {
+		return karma.Format(err, "clear muted status: %v", loggedUser(user))
+	}
+
+	err = watcher.store.Update(
+		bson.M{"user_id": user},
+		bson.M{"$set": bson.M{
+			"muted":        false,
+			"last_message": time.Now().Unix(),
+			"warned_at":    0,
+		}},
+	)
+	if err != nil && err != mgo.ErrNotFound {
+		return karma.Format(err, "reset timer: %v", loggedUser(user))
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) sendInvite(user *telebot.User) error {
+	link, err := watcher.bot.CreateInviteLink(
+		watcher.getChat(),
+		&telebot.ChatInviteLink{MemberLimit: 1},
+	)
+	if err != nil {
+		return karma.Format(err, "create invite link")
+	}
+
+	_, err = watcher.sendPrivate(
+		user,
+		"You have been pardoned, welcome back: "+link.InviteLink,
+	)
+	if err != nil {
+		return karma.Format(err, "send invite link")
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+func (watcher *Watcher) isPaused() bool {
+	return time.Now().Unix() < watcher.getSettings().PausedUntil
+}
+
+func (watcher *Watcher) handlePause(context telebot.Context) error {
+	args := context.Args()
+	if len(args) != 1 {
+		return context.Reply("Usage: /pause <duration>, e.g. /pause 2d or /pause 12h")
+	}
+
+	duration, err := parseDuration(args[0])
+	if err != nil || duration <= 0 {
+		return context.Reply("Invalid duration: " + args[0])
+	}
+
+	if watcher.isPaused() {
+		return context.Reply("Already paused, use /resume first.")
+	}
+
+	err = watcher.pause(duration, context.Sender().ID)
+	if err != nil {
+		return err
+	}
+
+	language := watcher.chatLanguage()
+
+	return context.Reply(
+		fmt.Sprintf(pausedTexts[language], humanDuration(duration, language)),
+	)
+}
+
+func (watcher *Watcher) handleResume(context telebot.Context) error {
+	if !watcher.isPaused() {
+		return context.Reply("Not paused.")
+	}
+
+	err := watcher.resume(context.Sender().ID)
+	if err != nil {
+		return err
+	}
+
+	return context.Reply("Resumed.")
+}
+
+func (watcher *Watcher) pause(duration time.Duration, actor int64) error {
+	log.Infof(nil, "pause for %v", duration)
+
+	err := watcher.updateSettings(actor, func(settings *Settings) {
+		settings.PausedUntil = time.Now().Add(duration).Unix()
+	})
+	if err != nil {
+		return err
+	}
+
+	return watcher.extendTimers(duration)
+}
+
+func (watcher *Watcher) resume(actor int64) error {
+	settings := watcher.getSettings()
+
+	remaining := time.Until(time.Unix(settings.PausedUntil, 0))
+
+	log.Infof(nil, "resume, %v of pause left", remaining)
+
+	err := watcher.updateSettings(actor, func(settings *Settings) {
+		settings.PausedUntil = 0
+	})
+	if err != nil {
+		return err
+	}
+
+	return watcher.extendTimers(-remaining)
+}
+
+func (watcher *Watcher) extendTimers(duration time.Duration) error {
+	_, err := watcher.store.UpdateAll(
+		bson.M{},
+		bson.M{"$inc": bson.M{"last_message": int64(duration.Seconds())}},
+	)
+	if err != nil {
+		return karma.Format(err, "extend timers by %v", duration)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/reconquest/karma-go"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const (
+	actionKick = "kick"
+	actionMute = "mute"
+)
+
+const (
+	day               = 24 * time.Hour
+	minPolicyDuration = day
+)
+
+type Policy struct {
+	Duration   time.Duration
+	WarnBefore time.Duration
+	Action     string
+}
+
+var presets = map[string]Policy{
+	"strict-30d": {
+		Duration: 30 * day,
+		Action:   actionKick,
+	},
+	"relaxed-90d-warn": {
+		Duration:   90 * day,
+		WarnBefore: 7 * day,
+		Action:     actionKick,
+	},
+	"mute-only": {
+		Duration:   30 * day,
+		WarnBefore: 3 * day,
+		Action:     actionMute,
+	},
+}
+
+func presetNames() []string {
+	names := []string{}
+	for name := range presets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func actionVerb(action string) string {
+	if action == actionMute {
+		return "muted"
+	}
+
+	return "removed"
+}
+
+func parsePolicyDuration(value string) (time.Duration, error) {
+	duration, err := parseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if duration < minPolicyDuration {
+		return 0, karma.Format(nil, "duration must be at least %v", minPolicyDuration)
+	}
+
+	return duration, nil
+}
+
+func (watcher *Watcher) policy() Policy {
+	settings := watcher.getSettings()
+
+	policy := watcher.defaultPolicy
+	if preset, ok := presets[settings.Preset]; ok {
+		policy = preset
+	}
+
+	if settings.Duration != "" {
+		duration, err := parsePolicyDuration(settings.Duration)
+		if err == nil {
+			policy.Duration = duration
+		}
+
+		if policy.WarnBefore >= policy.Duration {
+			policy.WarnBefore = 0
+		}
+	}
+
+	return policy
+}
+
+func (watcher *Watcher) mute(user int64) error {
+	err := watcher.bot.Restrict(watcher.getChat(), &telebot.ChatMember{
+		User:            &telebot.User{ID: user},
+		Rights:          telebot.NoRights(),
+		RestrictedUntil: telebot.Forever(),
+	})
+	return classifyBanError(user, err)
+}
+
+func (watcher *Watcher) unmute(user int64) error {
+	return watcher.bot.Restrict(watcher.getChat(), &telebot.ChatMember{
+		User:   &telebot.User{ID: user},
+		Rights: telebot.NoRestrictions(),
+	})
+}
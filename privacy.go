@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+func (watcher *Watcher) handleHideMe(context telebot.Context) error {
+	return watcher.setHidden(context, true)
+}
+
+func (watcher *Watcher) handleShowMe(context telebot.Context) error {
+	return watcher.setHidden(context, false)
+}
+
+func (watcher *Watcher) setHidden(context telebot.Context, hidden bool) error {
+	user := context.Sender()
+
+	log.Infof(nil, "set hidden: %v for user: %v", hidden, loggedUser(user.ID))
+
+	err := watcher.store.Update(
+		bson.M{"user_id": user.ID},
+		bson.M{"$set": bson.M{"hidden": hidden}},
+	)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return context.Reply("You are not tracked yet.")
+		}
+
+		return karma.Format(err, "set hidden: %v", loggedUser(user.ID))
+	}
+
+	if hidden {
+		return context.Reply("You are now hidden from public listings.")
+	}
+
+	return context.Reply("You are now visible in public listings.")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+)
+
+const pruneInterval = 6 * time.Hour
+
+var keptAuditActions = []string{auditKick, auditMute, auditSettings}
+
+var metricPruned = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "telekick_pruned_total",
+	Help: "Number of records removed by retention pruning.",
+}, []string{"collection"})
+
+func (watcher *Watcher) retentions() map[*mgo.Collection]time.Duration {
+	return map[*mgo.Collection]time.Duration{
+		watcher.audit:     watcher.auditRetention,
+		watcher.history:   watcher.historyRetention,
+		watcher.snapshots: watcher.snapshotRetention,
+		watcher.cycles:    watcher.historyRetention,
+	}
+}
+
+func (watcher *Watcher) Prune() {
+	for {
+		err := watcher.prune()
+		if err != nil {
+			log.Errorf(err, "prune")
+		}
+
+		time.Sleep(pruneInterval)
+	}
+}
+
+func (watcher *Watcher) prune() error {
+	for collection, retention := range watcher.retentions() {
+		if retention <= 0 {
+			continue
+		}
+
+		query := bson.M{
+			"time": bson.M{"$lt": time.Now().Add(-retention).Unix()},
+		}
+
+		if collection == watcher.audit {
+			query["action"] = bson.M{"$nin": keptAuditActions}
+		}
+
+		info, err := collection.RemoveAll(query)
+		if err != nil {
+			return karma.Format(err, "prune %s", collection.Name)
+		}
+
+		if info.Removed > 0 {
+			log.Infof(
+				nil,
+				"pruned %d records older than %v from %s",
+				info.Removed, retention, collection.Name,
+			)
+		}
+
+		metricPruned.WithLabelValues(collection.Name).Add(float64(info.Removed))
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+)
+
+var (
+	reQueryOr     = regexp.MustCompile(`(?i)\s+OR\s+`)
+	reQueryAnd    = regexp.MustCompile(`(?i)\s+AND\s+`)
+	reQueryClause = regexp.MustCompile(`^\s*(\w+)\s*(==|!=|>=|<=|>|<)\s*(\S+)\s*$`)
+)
+
+var queryOperators = map[string]string{
+	"==": "$eq",
+	"!=": "$ne",
+	">":  "$gt",
+	">=": "$gte",
+	"<":  "$lt",
+	"<=": "$lte",
+}
+
+var invertedOperators = map[string]string{
+	"==": "==",
+	"!=": "!=",
+	">":  "<",
+	">=": "<=",
+	"<":  ">",
+	"<=": ">=",
+}
+
+var queryBoolFields = map[string]string{
+	"unreachable": "unreachable",
+	"hidden":      "hidden",
+	"flagged":     "flagged",
+	"muted":       "muted",
+	"unbannable":  "unbannable",
+}
+
+func compileClause(clause string) (bson.M, error) {
+	matches := reQueryClause.FindStringSubmatch(clause)
+	if matches == nil {
+		return nil, karma.Format(nil, "invalid clause: %q", clause)
+	}
+
+	field, operator, value := matches[1], matches[2], matches[3]
+
+	switch field {
+	case "idle", "age":
+		duration, err := parseDuration(value)
+		if err != nil {
+			return nil, karma.Format(err, "invalid duration in clause: %q", clause)
+		}
+
+		key := "last_message"
+		if field == "age" {
+			key = "first_seen"
+		}
+
+		// idle > 30d means the timestamp is older than 30 days ago.
+		moment := time.Now().Add(-duration).Unix()
+
+		return bson.M{
+			key: bson.M{queryOperators[invertedOperators[operator]]: moment},
+		}, nil
+
+	case "messages", "messages_30d", "user_id":
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, karma.Format(err, "invalid number in clause: %q", clause)
+		}
+
+		return bson.M{field: bson.M{queryOperators[operator]: number}}, nil
+
+	case "username":
+		if operator != "==" && operator != "!=" {
+			return nil, karma.Format(nil, "username supports only == and !=")
+		}
+
+		return bson.M{
+			field: bson.M{queryOperators[operator]: strings.TrimPrefix(value, "@")},
+		}, nil
+
+	case "warned":
+		flag, err := strconv.ParseBool(value)
+		if err != nil || (operator != "==" && operator != "!=") {
+			return nil, karma.Format(err, "invalid clause: %q", clause)
+		}
+
+		if operator == "!=" {
+			flag = !flag
+		}
+
+		if flag {
+			return bson.M{"warned_at": bson.M{"$gt": 0}}, nil
+		}
+
+		return bson.M{"warned_at": bson.M{"$in": []interface{}{0, nil}}}, nil
+	}
+
+	key, ok := queryBoolFields[field]
+	if !ok {
+		return nil, karma.Format(nil, "unknown field: %q", field)
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil || (operator != "==" && operator != "!=") {
+		return nil, karma.Format(err, "invalid clause: %q", clause)
+	}
+
+	if operator == "!=" {
+		flag = !flag
+	}
+
+	if flag {
+		return bson.M{key: true}, nil
+	}
+
+	return bson.M{key: bson.M{"$ne": true}}, nil
+}
+
+func compileQuery(expression string) (bson.M, error) {
+	alternatives := []bson.M{}
+	for _, alternative := range reQueryOr.Split(strings.TrimSpace(expression), -1) {
+		clauses := []bson.M{}
+		for _, clause := range reQueryAnd.Split(alternative, -1) {
+			compiled, err := compileClause(clause)
+			if err != nil {
+				return nil, err
+			}
+
+			clauses = append(clauses, compiled)
+		}
+
+		alternatives = append(alternatives, bson.M{"$and": clauses})
+	}
+
+	if len(alternatives) == 1 {
+		return alternatives[0], nil
+	}
+
+	return bson.M{"$or": alternatives}, nil
+}
+
+func (watcher *Watcher) query(expression string) ([]User, error) {
+	query, err := compileQuery(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []User
+	err = watcher.store.Find(query).Sort("last_message", "user_id").All(&users)
+	if err != nil {
+		return nil, karma.Format(err, "find users")
+	}
+
+	return users, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+func TestCompileClause(t *testing.T) {
+	tests := []struct {
+		clause string
+		want   bson.M
+	}{
+		{"messages > 10", bson.M{"messages": bson.M{"$gt": int64(10)}}},
+		{"messages<=3", bson.M{"messages": bson.M{"$lte": int64(3)}}},
+		{"messages_30d == 0", bson.M{"messages_30d": bson.M{"$eq": int64(0)}}},
+		{"user_id == 42", bson.M{"user_id": bson.M{"$eq": int64(42)}}},
+		{"username == @john", bson.M{"username": bson.M{"$eq": "john"}}},
+		{"username != john", bson.M{"username": bson.M{"$ne": "john"}}},
+		{"warned == true", bson.M{"warned_at": bson.M{"$gt": 0}}},
+		{"warned != true", bson.M{"warned_at": bson.M{"$in": []interface{}{0, nil}}}},
+		{"muted == true", bson.M{"muted": true}},
+		{"hidden == false", bson.M{"hidden": bson.M{"$ne": true}}},
+		{"unreachable != false", bson.M{"unreachable": true}},
+	}
+
+	for _, test := range tests {
+		got, err := compileClause(test.clause)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.clause, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.clause, got, test.want)
+		}
+	}
+}
+
+func TestCompileClauseInvertsDurations(t *testing.T) {
+	tests := []struct {
+		clause   string
+		key      string
+		operator string
+		duration time.Duration
+	}{
+		{"idle > 30d", "last_message", "$lt", 30 * day},
+		{"idle >= 12h", "last_message", "$lte", 12 * time.Hour},
+		{"idle < 1d", "last_message", "$gt", day},
+		{"idle <= 1d", "last_message", "$gte", day},
+		{"idle == 1h", "last_message", "$eq", time.Hour},
+		{"age > 7d", "first_seen", "$lt", 7 * day},
+	}
+
+	for _, test := range tests {
+		before := time.Now().Add(-test.duration).Unix()
+
+		got, err := compileClause(test.clause)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.clause, err)
+			continue
+		}
+
+		after := time.Now().Add(-test.duration).Unix()
+
+		condition, ok := got[test.key].(bson.M)
+		if !ok || len(got) != 1 {
+			t.Errorf("%q: unexpected query: %v", test.clause, got)
+			continue
+		}
+
+		moment, ok := condition[test.operator].(int64)
+		if !ok || len(condition) != 1 {
+			t.Errorf("%q: got %v, want operator %s", test.clause, condition, test.operator)
+			continue
+		}
+
+		if moment < before || moment > after {
+			t.Errorf("%q: got moment %d, want %d", test.clause, moment, before)
+		}
+	}
+}
+
+func TestCompileClauseErrors(t *testing.T) {
+	clauses := []string{
+		"",
+		"messages",
+		"messages > many",
+		"idle > soon",
+		"username > john",
+		"warned == maybe",
+		"muted > true",
+		"karma > 10",
+	}
+
+	for _, clause := range clauses {
+		_, err := compileClause(clause)
+		if err == nil {
+			t.Errorf("%q: expected error", clause)
+		}
+	}
+}
+
+func TestCompileQuery(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       bson.M
+	}{
+		{
+			"messages > 1",
+			bson.M{"$and": []bson.M{
+				{"messages": bson.M{"$gt": int64(1)}},
+			}},
+		},
+		{
+			"messages > 1 AND muted == true",
+			bson.M{"$and": []bson.M{
+				{"messages": bson.M{"$gt": int64(1)}},
+				{"muted": true},
+			}},
+		},
+		{
+			"messages > 1 and muted == true or hidden == true",
+			bson.M{"$or": []bson.M{
+				{"$and": []bson.M{
+					{"messages": bson.M{"$gt": int64(1)}},
+					{"muted": true},
+				}},
+				{"$and": []bson.M{
+					{"hidden": true},
+				}},
+			}},
+		},
+		{
+			"muted == true OR hidden == true AND messages < 5",
+			bson.M{"$or": []bson.M{
+				{"$and": []bson.M{
+					{"muted": true},
+				}},
+				{"$and": []bson.M{
+					{"hidden": true},
+					{"messages": bson.M{"$lt": int64(5)}},
+				}},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := compileQuery(test.expression)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.expression, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.expression, got, test.want)
+		}
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	expressions := []string{
+		"messages > 1 AND",
+		"messages > 1 OR karma > 2",
+	}
+
+	for _, expression := range expressions {
+		_, err := compileQuery(expression)
+		if err == nil {
+			t.Errorf("%q: expected error", expression)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/pkg/log"
+)
+
+const replayInterval = 10 * time.Second
+
+type Activity struct {
+	UserID    int64
+	Username  string
+	Timestamp int64
+}
+
+type ActivityQueue struct {
+	mutex      sync.Mutex
+	size       int
+	activities map[int64]Activity
+}
+
+func NewActivityQueue(size int) *ActivityQueue {
+	return &ActivityQueue{
+		size:       size,
+		activities: map[int64]Activity{},
+	}
+}
+
+func (queue *ActivityQueue) Push(activity Activity) bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	previous, ok := queue.activities[activity.UserID]
+	if !ok && len(queue.activities) >= queue.size {
+		return false
+	}
+
+	if ok && previous.Timestamp > activity.Timestamp {
+		return true
+	}
+
+	queue.activities[activity.UserID] = activity
+
+	return true
+}
+
+func (queue *ActivityQueue) Drain() []Activity {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	activities := make([]Activity, 0, len(queue.activities))
+	for _, activity := range queue.activities {
+		activities = append(activities, activity)
+	}
+
+	queue.activities = map[int64]Activity{}
+
+	return activities
+}
+
+func (queue *ActivityQueue) Len() int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	return len(queue.activities)
+}
+
+func (watcher *Watcher) enqueueActivity(activity Activity) {
+	if !watcher.pending.Push(activity) {
+		log.Errorf(
+			nil,
+			"activity queue is full, dropping update for user: %v",
+			loggedUser(activity.UserID),
+		)
+	}
+}
+
+func (watcher *Watcher) ReplayActivity() {
+	for range time.Tick(replayInterval) {
+		watcher.replayActivity()
+	}
+}
+
+func (watcher *Watcher) replayActivity() {
+	if watcher.pending.Len() == 0 {
+		return
+	}
+
+	watcher.store.Database.Session.Refresh()
+
+	activities := watcher.pending.Drain()
+
+	log.Infof(nil, "replaying %d queued activity updates", len(activities))
+
+	for _, activity := range activities {
+		set := bson.M{"user_id": activity.UserID}
+		if activity.Username != "" {
+			set["username"] = activity.Username
+		}
+
+		_, err := watcher.store.Upsert(
+			bson.M{"user_id": activity.UserID},
+			bson.M{
+				"$set":         set,
+				"$max":         bson.M{"last_message": activity.Timestamp},
+				"$setOnInsert": bson.M{"first_seen": activity.Timestamp},
+			},
+		)
+		if err != nil {
+			log.Errorf(err, "replay activity for user: %v", loggedUser(activity.UserID))
+			watcher.enqueueActivity(activity)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	telebot "gopkg.in/telebot.v3"
+)
+
+var logRedactKey []byte
+
+func loggedUser(id int64) interface{} {
+	if logRedactKey == nil {
+		return id
+	}
+
+	mac := hmac.New(sha256.New, logRedactKey)
+	mac.Write([]byte(strconv.FormatInt(id, 10)))
+
+	return "user:" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+func loggedName(name string) string {
+	if logRedactKey == nil {
+		return name
+	}
+
+	return "[redacted]"
+}
+
+func loggedRecipient(recipient telebot.Recipient) interface{} {
+	id, err := strconv.ParseInt(recipient.Recipient(), 10, 64)
+	if err != nil {
+		return loggedName(recipient.Recipient())
+	}
+
+	return loggedUser(id)
+}
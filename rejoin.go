@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+func (watcher *Watcher) wasKicked(user int64) (bool, error) {
+	count, err := watcher.audit.Find(bson.M{
+		"action":  auditKick,
+		"user_id": user,
+		"active":  true,
+	}).Count()
+	if err != nil {
+		return false, karma.Format(err, "find kicks: %v", loggedUser(user))
+	}
+
+	return count > 0, nil
+}
+
+func (watcher *Watcher) handleRejoin(context telebot.Context) error {
+	user := context.Message().UserJoined
+
+	kicked, err := watcher.wasKicked(user.ID)
+	if err != nil || !kicked {
+		return err
+	}
+
+	until := time.Now().Add(watcher.rejoinGrace)
+
+	log.Infof(nil, "kicked user %v rejoined, grace until %v", loggedUser(user.ID), until)
+
+	err = watcher.store.Update(
+		bson.M{"user_id": user.ID},
+		bson.M{"$set": bson.M{
+			"last_message": until.Add(-watcher.policy().Duration).Unix(),
+			"grace_until":  until.Unix(),
+			"warned_at":    0,
+		}},
+	)
+	if err != nil {
+		return karma.Format(err, "grant rejoin grace: %v", loggedUser(user.ID))
+	}
+
+	err = watcher.record(AuditRecord{
+		UserID:   user.ID,
+		Username: user.Username,
+		Action:   auditRejoin,
+		Details:  "grace until " + until.Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Error(err)
+	}
+
+	language := watcher.chatLanguage()
+
+	text := fmt.Sprintf(
+		rejoinGreetingTexts[language],
+		displayName(user.Username, user.FirstName, user.LastName),
+		humanDuration(watcher.rejoinGrace, language),
+	)
+	if watcher.rejoinRules != "" {
+		text += "\n\n" + watcher.rejoinRules
+	}
+
+	return context.Reply(text)
+}
+
+func (watcher *Watcher) handleWhy(context telebot.Context) error {
+	target := context.Sender()
+
+	if reply := context.Message().ReplyTo; reply != nil && reply.Sender != nil {
+		admin, err := watcher.isAdmin(context.Sender())
+		if err != nil {
+			return err
+		}
+
+		if admin {
+			target = reply.Sender
+		}
+	}
+
+	language := watcher.userLanguage(context.Sender().LanguageCode)
+	texts := whyTexts[language]
+
+	var user User
+	err := watcher.store.Find(bson.M{"user_id": target.ID}).One(&user)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return context.Reply(texts.unseen)
+		}
+
+		return karma.Format(err, "find user: %v", loggedUser(target.ID))
+	}
+
+	policy := watcher.policy()
+	now := time.Now()
+	lastMessage := time.Unix(user.LastMessage, 0)
+
+	lines := []string{}
+	if lastMessage.After(now) {
+		lines = append(lines, texts.noMessages)
+	} else {
+		lines = append(
+			lines,
+			fmt.Sprintf(texts.lastMessage, humanAgo(now.Sub(lastMessage), language)),
+		)
+	}
+
+	if user.GraceUntil > now.Unix() {
+		lines = append(
+			lines,
+			fmt.Sprintf(
+				texts.grace,
+				time.Unix(user.GraceUntil, 0).Format(time.RFC1123),
+			),
+		)
+	}
+
+	if user.WarnedAt > 0 {
+		lines = append(
+			lines,
+			fmt.Sprintf(
+				texts.warned,
+				time.Unix(user.WarnedAt, 0).Format(time.RFC1123),
+			),
+		)
+	}
+
+	exempt, provider, err := watcher.exempt(user)
+	if err != nil {
+		return karma.Format(err, "check exemption of %v", loggedUser(user.UserID))
+	}
+
+	switch {
+	case user.Muted:
+		lines = append(lines, texts.muted)
+	case exempt:
+		lines = append(lines, fmt.Sprintf(texts.exempt, provider))
+	default:
+		verb := actionVerb(policy.Action)
+		if language == languageRussian {
+			verb = auditActionName(policy.Action, language)
+		}
+
+		lines = append(
+			lines,
+			fmt.Sprintf(
+				texts.deadline,
+				verb,
+				lastMessage.Add(policy.Duration).Format(time.RFC1123),
+			),
+		)
+	}
+
+	return context.Reply(strings.Join(lines, "\n"))
+}
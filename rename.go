@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const (
+	renameFlag  = "flag"
+	renameReset = "reset"
+)
+
+func displayName(username, firstName, lastName string) string {
+	return strings.TrimSpace("@" + username + " " + firstName + " " + lastName)
+}
+
+func (watcher *Watcher) detectRename(user *telebot.User) error {
+	var known User
+	err := watcher.store.Find(bson.M{"user_id": user.ID}).One(&known)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil
+		}
+
+		return karma.Format(err, "find user: %v", loggedUser(user.ID))
+	}
+
+	if known.Username == "" && known.FirstName == "" {
+		return nil
+	}
+
+	if known.Username == user.Username &&
+		known.FirstName == user.FirstName &&
+		known.LastName == user.LastName {
+		return nil
+	}
+
+	previous := displayName(known.Username, known.FirstName, known.LastName)
+	current := displayName(user.Username, user.FirstName, user.LastName)
+
+	log.Infof(
+		nil,
+		"user: %v renamed from %q to %q, action: %s",
+		loggedUser(user.ID),
+		loggedName(previous),
+		loggedName(current),
+		watcher.renameAction,
+	)
+
+	update := bson.M{"flagged": true}
+	if watcher.renameAction == renameReset {
+		update["first_seen"] = time.Now().Unix()
+		update["messages"] = 0
+		update["messages_30d"] = 0
+		update["message_days"] = bson.M{}
+	}
+
+	err = watcher.store.Update(
+		bson.M{"user_id": user.ID},
+		bson.M{"$set": update},
+	)
+	if err != nil {
+		return karma.Format(err, "flag renamed user: %v", loggedUser(user.ID))
+	}
+
+	return watcher.record(AuditRecord{
+		UserID:   user.ID,
+		Username: user.Username,
+		Action:   auditRename,
+		Details:  previous + " → " + current,
+	})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/reconquest/karma-go"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const (
+	reportCSV  = "csv"
+	reportJSON = "json"
+)
+
+type ReportRow struct {
+	UserID      int64  `json:"user_id"`
+	Username    string `json:"username"`
+	LastMessage string `json:"last_message"`
+	Idle        string `json:"idle"`
+	Messages    int64  `json:"messages"`
+	Messages30d int64  `json:"messages_30d"`
+}
+
+func renderReport(users []User, format string) ([]byte, error) {
+	rows := []ReportRow{}
+	for _, user := range users {
+		timestamp := time.Unix(user.LastMessage, 0)
+
+		rows = append(rows, ReportRow{
+			UserID:      user.UserID,
+			Username:    user.Username,
+			LastMessage: timestamp.Format(time.RFC3339),
+			Idle:        time.Since(timestamp).Round(time.Second).String(),
+			Messages:    user.Messages,
+			Messages30d: user.Messages30d,
+		})
+	}
+
+	var buffer bytes.Buffer
+
+	switch format {
+	case reportJSON:
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetIndent("", "  ")
+
+		err := encoder.Encode(rows)
+		if err != nil {
+			return nil, karma.Format(err, "encode json report")
+		}
+
+	case reportCSV:
+		writer := csv.NewWriter(&buffer)
+		writer.Write([]string{
+			"user_id", "username", "last_message", "idle",
+			"messages", "messages_30d",
+		})
+
+		for _, row := range rows {
+			writer.Write([]string{
+				strconv.FormatInt(row.UserID, 10),
+				row.Username,
+				row.LastMessage,
+				row.Idle,
+				strconv.FormatInt(row.Messages, 10),
+				strconv.FormatInt(row.Messages30d, 10),
+			})
+		}
+
+		writer.Flush()
+
+		err := writer.Error()
+		if err != nil {
+			return nil, karma.Format(err, "encode csv report")
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown report format: %q", format)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (watcher *Watcher) sendReport(
+	to telebot.Recipient,
+	summary *Summary,
+	format string,
+) error {
+	data, err := renderReport(summary.Users, format)
+	if err != nil {
+		return err
+	}
+
+	caption := fmt.Sprintf("%d users would be kicked", len(summary.Users))
+	if summary.Skipped != "" {
+		caption = "Enforcement would be skipped: " + summary.Skipped
+	}
+
+	caption += "\nCycle: " + summary.Cycle
+
+	_, err = watcher.bot.Send(to, &telebot.Document{
+		File:     telebot.FromReader(bytes.NewReader(data)),
+		FileName: "dry-run-" + time.Unix(summary.Time, 0).Format("20060102-150405") + "." + format,
+		Caption:  caption,
+	})
+	if err != nil {
+		return karma.Format(err, "send report")
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) handleDryRun(context telebot.Context) error {
+	format := reportCSV
+	if args := context.Args(); len(args) > 0 {
+		format = args[0]
+	}
+
+	if format != reportCSV && format != reportJSON {
+		return context.Reply("Usage: /dryrun [csv|json]")
+	}
+
+	summary, err := watcher.enforce(true)
+	if err != nil {
+		return err
+	}
+
+	err = watcher.sendReport(context.Sender(), summary, format)
+	if err != nil {
+		if isForbidden(err) {
+			watcher.setUnreachable(context.Sender().ID, true)
+			return context.Reply("Please start a private chat with me first.")
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+func parseRolloutSince(value string) (time.Time, error) {
+	moment, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		return moment, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+func (watcher *Watcher) rolloutFilter(query bson.M) bson.M {
+	if !watcher.rolloutSince.IsZero() {
+		query["joined_at"] = bson.M{"$gte": watcher.rolloutSince.Unix()}
+	}
+
+	if watcher.rolloutPercent > 0 && watcher.rolloutPercent < 100 {
+		buckets := []bson.M{}
+		for bucket := 0; bucket < watcher.rolloutPercent; bucket++ {
+			buckets = append(buckets, bson.M{
+				"user_id": bson.M{"$mod": []int{100, bucket}},
+			})
+		}
+
+		query["$or"] = buckets
+	}
+
+	return query
+}
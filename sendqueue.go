@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const sendRetries = 3
+
+type SendQueue struct {
+	bot      *telebot.Bot
+	interval time.Duration
+	last     time.Time
+	mutex    sync.Mutex
+}
+
+func NewSendQueue(bot *telebot.Bot, interval time.Duration) *SendQueue {
+	return &SendQueue{
+		bot:      bot,
+		interval: interval,
+	}
+}
+
+func (queue *SendQueue) wait() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if delay := queue.interval - time.Since(queue.last); delay > 0 {
+		time.Sleep(delay)
+	}
+
+	queue.last = time.Now()
+}
+
+func (queue *SendQueue) Send(
+	to telebot.Recipient,
+	what interface{},
+	options ...interface{},
+) (*telebot.Message, error) {
+	for attempt := 1; ; attempt++ {
+		queue.wait()
+
+		message, err := queue.bot.Send(to, what, options...)
+		if err == nil {
+			return message, nil
+		}
+
+		flood, ok := err.(telebot.FloodError)
+		if !ok || attempt >= sendRetries {
+			return nil, err
+		}
+
+		log.Warningf(
+			nil,
+			"flood limit hit sending to %v, retrying in %vs",
+			loggedRecipient(to), flood.RetryAfter,
+		)
+
+		time.Sleep(time.Duration(flood.RetryAfter) * time.Second)
+	}
+}
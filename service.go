@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+var serviceEndpoints = []string{
+	telebot.OnPinned,
+	telebot.OnNewGroupTitle,
+	telebot.OnNewGroupPhoto,
+	telebot.OnGroupPhotoDeleted,
+	telebot.OnVideoChatStarted,
+	telebot.OnVideoChatEnded,
+	telebot.OnVideoChatParticipants,
+	telebot.OnVideoChatScheduled,
+	telebot.OnProximityAlert,
+	telebot.OnAutoDeleteTimer,
+	telebot.OnTopicCreated,
+	telebot.OnTopicReopened,
+	telebot.OnTopicClosed,
+	telebot.OnTopicEdited,
+	telebot.OnGeneralTopicHidden,
+	telebot.OnGeneralTopicUnhidden,
+}
+
+func serviceKind(endpoint string) string {
+	return strings.TrimPrefix(endpoint, "\a")
+}
+
+func parseServiceActivity(kinds []string) map[string]bool {
+	known := map[string]bool{}
+	for _, endpoint := range serviceEndpoints {
+		known[serviceKind(endpoint)] = true
+	}
+
+	result := map[string]bool{}
+	for _, kind := range kinds {
+		if !known[kind] {
+			log.Fatalf(nil, "unknown service message type: %q", kind)
+		}
+
+		result[kind] = true
+	}
+
+	return result
+}
+
+func (watcher *Watcher) handleServiceMessage(endpoint string) telebot.HandlerFunc {
+	kind := serviceKind(endpoint)
+
+	return func(context telebot.Context) error {
+		if !watcher.serviceActivity[kind] {
+			log.Debugf(nil, "service message %s does not count as activity", kind)
+			return nil
+		}
+
+		return watcher.handleActivity(context)
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+type Settings struct {
+	ChatID      int64  `bson:"chat_id" yaml:"chat_id"`
+	MigratedTo  int64  `bson:"migrated_to,omitempty" yaml:"migrated_to,omitempty"`
+	PausedUntil int64  `bson:"paused_until" yaml:"paused_until,omitempty"`
+	Preset      string `bson:"preset,omitempty" yaml:"preset,omitempty"`
+	Language    string `bson:"language,omitempty" yaml:"language,omitempty"`
+	Unbannable  string `bson:"unbannable,omitempty" yaml:"unbannable,omitempty"`
+	Duration    string `bson:"duration,omitempty" yaml:"duration,omitempty"`
+
+	Topics map[string]string `bson:"topics,omitempty" yaml:"topics,omitempty"`
+}
+
+func (watcher *Watcher) getChat() *telebot.Chat {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	return watcher.chat
+}
+
+func (watcher *Watcher) setChat(id int64) {
+	watcher.mutex.Lock()
+	watcher.chat = &telebot.Chat{ID: id}
+	watcher.mutex.Unlock()
+}
+
+func (watcher *Watcher) loadSettings() error {
+	chat := watcher.getChat()
+
+	settings := Settings{ChatID: chat.ID}
+
+	err := watcher.settingsStore.Find(
+		bson.M{"chat_id": chat.ID},
+	).One(&settings)
+	if err != nil && err != mgo.ErrNotFound {
+		return karma.Format(err, "find settings: %v", chat.ID)
+	}
+
+	if settings.MigratedTo != 0 {
+		log.Warningf(
+			nil,
+			"chat %v was migrated to %v, please update TELEGRAM_CHAT",
+			chat.ID, settings.MigratedTo,
+		)
+
+		watcher.setChat(settings.MigratedTo)
+
+		return watcher.loadSettings()
+	}
+
+	watcher.mutex.Lock()
+	watcher.settings = settings
+	watcher.mutex.Unlock()
+
+	return nil
+}
+
+func (watcher *Watcher) ReloadSettings() {
+	for {
+		time.Sleep(watcher.settingsReload)
+
+		changes, err := watcher.reloadSettings()
+		if err != nil {
+			log.Errorf(err, "reload settings")
+			continue
+		}
+
+		for _, change := range changes {
+			log.Infof(
+				nil,
+				"setting %s reloaded: %q -> %q",
+				change.Field, change.From, change.To,
+			)
+		}
+	}
+}
+
+func (watcher *Watcher) reloadSettings() ([]settingChange, error) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	settings := Settings{ChatID: watcher.chat.ID}
+
+	err := watcher.settingsStore.Find(
+		bson.M{"chat_id": watcher.chat.ID},
+	).One(&settings)
+	if err != nil && err != mgo.ErrNotFound {
+		return nil, karma.Format(err, "find settings: %v", watcher.chat.ID)
+	}
+
+	if settings.MigratedTo != 0 {
+		return nil, nil
+	}
+
+	changes := diffSettings(watcher.settings, settings)
+
+	watcher.settings = settings
+
+	return changes, nil
+}
+
+func (watcher *Watcher) getSettings() Settings {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	return watcher.settings
+}
+
+func (watcher *Watcher) saveSettings(settings Settings) error {
+	settings.ChatID = watcher.getChat().ID
+
+	_, err := watcher.settingsStore.Upsert(
+		bson.M{"chat_id": settings.ChatID},
+		settings,
+	)
+	if err != nil {
+		return karma.Format(err, "save settings: %v", settings.ChatID)
+	}
+
+	watcher.mutex.Lock()
+	watcher.settings = settings
+	watcher.mutex.Unlock()
+
+	return nil
+}
+
+type settingChange struct {
+	Field string
+	From  string
+	To    string
+	Value interface{}
+}
+
+func formatPausedUntil(until int64) string {
+	if until == 0 {
+		return "none"
+	}
+
+	return time.Unix(until, 0).Format(time.RFC3339)
+}
+
+func diffSettings(old, new Settings) []settingChange {
+	changes := []settingChange{}
+
+	if old.Preset != new.Preset {
+		changes = append(changes, settingChange{
+			"preset", old.Preset, new.Preset, new.Preset,
+		})
+	}
+
+	if old.Language != new.Language {
+		changes = append(changes, settingChange{
+			"language", old.Language, new.Language, new.Language,
+		})
+	}
+
+	if old.Unbannable != new.Unbannable {
+		changes = append(changes, settingChange{
+			"unbannable", old.Unbannable, new.Unbannable, new.Unbannable,
+		})
+	}
+
+	if old.Duration != new.Duration {
+		changes = append(changes, settingChange{
+			"duration", old.Duration, new.Duration, new.Duration,
+		})
+	}
+
+	if old.PausedUntil != new.PausedUntil {
+		changes = append(changes, settingChange{
+			"paused_until",
+			formatPausedUntil(old.PausedUntil),
+			formatPausedUntil(new.PausedUntil),
+			new.PausedUntil,
+		})
+	}
+
+	if from, to := fmt.Sprint(old.Topics), fmt.Sprint(new.Topics); from != to {
+		changes = append(changes, settingChange{"topics", from, to, new.Topics})
+	}
+
+	return changes
+}
+
+func (watcher *Watcher) recordSettingChanges(
+	actor int64,
+	prefix string,
+	changes []settingChange,
+) {
+	for _, change := range changes {
+		err := watcher.record(AuditRecord{
+			Action: auditSettings,
+			Actor:  actor,
+			Details: fmt.Sprintf(
+				"%s%s: %q -> %q",
+				prefix, change.Field, change.From, change.To,
+			),
+		})
+		if err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+func (watcher *Watcher) updateSettings(
+	actor int64,
+	update func(settings *Settings),
+) error {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	settings := watcher.settings
+	settings.ChatID = watcher.chat.ID
+
+	settings.Topics = map[string]string{}
+	for id, rule := range watcher.settings.Topics {
+		settings.Topics[id] = rule
+	}
+
+	update(&settings)
+
+	changes := diffSettings(watcher.settings, settings)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	set := bson.M{}
+	for _, change := range changes {
+		set[change.Field] = change.Value
+	}
+
+	_, err := watcher.settingsStore.Upsert(
+		bson.M{"chat_id": settings.ChatID},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return karma.Format(err, "save settings: %v", settings.ChatID)
+	}
+
+	watcher.settings = settings
+
+	watcher.recordSettingChanges(actor, "", changes)
+
+	return nil
+}
+
+func (watcher *Watcher) handleSettingsHistory(context telebot.Context) error {
+	var records []AuditRecord
+	err := watcher.audit.Find(
+		bson.M{"action": auditSettings},
+	).Sort("-time").Limit(logMax).All(&records)
+	if err != nil {
+		return karma.Format(err, "find settings history")
+	}
+
+	if len(records) == 0 {
+		return context.Reply(emptySettingsHistoryTexts[watcher.chatLanguage()])
+	}
+
+	lines := []string{}
+	for i := len(records) - 1; i >= 0; i-- {
+		lines = append(lines, formatAuditRecord(records[i], watcher.chatLanguage()))
+	}
+
+	return context.Reply(strings.Join(lines, "\n"))
+}
+
+func (watcher *Watcher) handleSettings(context telebot.Context) error {
+	args := context.Args()
+	if len(args) == 1 && args[0] == "history" {
+		return watcher.handleSettingsHistory(context)
+	}
+
+	if len(args) > 0 {
+		return watcher.handleSettingsChange(context, args)
+	}
+
+	settings := watcher.getSettings()
+	policy := watcher.policy()
+	language := watcher.chatLanguage()
+
+	preset := settings.Preset
+	if preset == "" {
+		preset = "none"
+	}
+
+	status := "active"
+	if watcher.isPaused() {
+		status = "paused until " +
+			time.Unix(settings.PausedUntil, 0).Format(time.RFC1123)
+	}
+
+	return context.Reply(fmt.Sprintf(
+		"Preset: %s\n"+
+			"Language: %s\n"+
+			"Inactivity threshold: %s\n"+
+			"Warning before: %s\n"+
+			"Action: %s\n"+
+			"Unbannable users: %s\n"+
+			"Status: %s",
+		preset,
+		language,
+		humanDuration(policy.Duration, language),
+		humanDuration(policy.WarnBefore, language),
+		policy.Action,
+		watcher.unbannableAction(),
+		status,
+	))
+}
+
+func settingsUpdate(key, value string) func(settings *Settings) {
+	switch key {
+	case "preset":
+		if _, ok := presets[value]; !ok && value != "none" {
+			return nil
+		}
+
+		if value == "none" {
+			value = ""
+		}
+
+		return func(settings *Settings) {
+			settings.Preset = value
+		}
+
+	case "language":
+		language := supportedLanguage(value)
+		if language == "" {
+			return nil
+		}
+
+		return func(settings *Settings) {
+			settings.Language = language
+		}
+
+	case "unbannable":
+		if value != unbannableRetry &&
+			value != unbannableSkip &&
+			value != unbannableNotify {
+			return nil
+		}
+
+		return func(settings *Settings) {
+			settings.Unbannable = value
+		}
+
+	case "duration":
+		if value == "none" {
+			value = ""
+		} else if _, err := parsePolicyDuration(value); err != nil {
+			return nil
+		}
+
+		return func(settings *Settings) {
+			settings.Duration = value
+		}
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) handleSettingsChange(
+	context telebot.Context,
+	args []string,
+) error {
+	admin, err := watcher.isAdmin(context.Sender())
+	if err != nil {
+		return err
+	}
+
+	if !admin {
+		return context.Reply("Only admins can change settings.")
+	}
+
+	if watcher.standby {
+		return context.Reply(standbyNotice)
+	}
+
+	usage := "Usage:\n" +
+		"/settings preset <" +
+		strings.Join(append(presetNames(), "none"), "|") + ">\n" +
+		"/settings language <" + strings.Join(languages, "|") + ">\n" +
+		"/settings unbannable <" + strings.Join(unbannableActions, "|") + ">\n" +
+		"/settings duration <30d|none>\n" +
+		"/settings history"
+
+	if len(args) != 2 {
+		return context.Reply(usage)
+	}
+
+	update := settingsUpdate(args[0], args[1])
+	if update == nil {
+		return context.Reply(usage)
+	}
+
+	log.Infof(
+		nil,
+		"setting %s changed to %q by %v",
+		args[0], args[1], loggedUser(context.Sender().ID),
+	)
+
+	err = watcher.updateSettings(context.Sender().ID, update)
+	if err != nil {
+		return err
+	}
+
+	return context.Reply("Settings applied.")
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+)
+
+const (
+	snapshotChunk    = 1000
+	snapshotInterval = day
+)
+
+type Snapshot struct {
+	Time    int64   `bson:"time"`
+	Members []int64 `bson:"members"`
+}
+
+type Churn struct {
+	Since   time.Time `json:"since"`
+	Joined  []int64   `json:"joined"`
+	Left    []int64   `json:"left"`
+	Kicked  []int64   `json:"kicked"`
+	Removed []int64   `json:"removed"`
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(days) * day, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+func (watcher *Watcher) members() ([]int64, error) {
+	var members []int64
+	err := watcher.store.Find(nil).Distinct("user_id", &members)
+	if err != nil {
+		return nil, karma.Format(err, "find members")
+	}
+
+	return members, nil
+}
+
+func (watcher *Watcher) saveSnapshot() error {
+	var last Snapshot
+	err := watcher.snapshots.Find(nil).
+		Select(bson.M{"time": 1}).
+		Sort("-time").
+		One(&last)
+	switch {
+	case err == nil:
+		if time.Since(time.Unix(last.Time, 0)) < snapshotInterval {
+			return nil
+		}
+	case err != mgo.ErrNotFound:
+		return karma.Format(err, "find last snapshot")
+	}
+
+	snapshot := Snapshot{Time: time.Now().Unix()}
+	saved := false
+
+	iter := watcher.store.Find(nil).
+		Select(bson.M{"user_id": 1}).
+		Batch(enforceBatch).
+		Iter()
+
+	var user User
+	for iter.Next(&user) {
+		snapshot.Members = append(snapshot.Members, user.UserID)
+
+		if len(snapshot.Members) < snapshotChunk {
+			continue
+		}
+
+		err := watcher.snapshots.Insert(snapshot)
+		if err != nil {
+			iter.Close()
+			return karma.Format(err, "save snapshot")
+		}
+
+		snapshot.Members = nil
+		saved = true
+	}
+
+	err = iter.Close()
+	if err != nil {
+		return karma.Format(err, "find members")
+	}
+
+	if len(snapshot.Members) > 0 || !saved {
+		err = watcher.snapshots.Insert(snapshot)
+		if err != nil {
+			return karma.Format(err, "save snapshot")
+		}
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) diff(since time.Duration) (*Churn, error) {
+	moment := time.Now().Add(-since)
+
+	var snapshot Snapshot
+	err := watcher.snapshots.Find(
+		bson.M{"time": bson.M{"$lte": moment.Unix()}},
+	).Sort("-time").One(&snapshot)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, karma.Format(
+				nil,
+				"no snapshot taken before %v",
+				moment.Format(time.RFC3339),
+			)
+		}
+
+		return nil, karma.Format(err, "find snapshot")
+	}
+
+	members, err := watcher.members()
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []int64
+
+	before := map[int64]bool{}
+	var chunk Snapshot
+	iter := watcher.snapshots.Find(bson.M{"time": snapshot.Time}).Iter()
+	for iter.Next(&chunk) {
+		for _, member := range chunk.Members {
+			before[member] = true
+			previous = append(previous, member)
+		}
+	}
+
+	err = iter.Close()
+	if err != nil {
+		return nil, karma.Format(err, "find snapshot members")
+	}
+
+	churn := &Churn{Since: time.Unix(snapshot.Time, 0)}
+
+	now := map[int64]bool{}
+	for _, member := range members {
+		now[member] = true
+		if !before[member] {
+			churn.Joined = append(churn.Joined, member)
+		}
+	}
+
+	var records []AuditRecord
+	err = watcher.audit.Find(bson.M{
+		"action": bson.M{"$in": []string{auditKick, auditRemove}},
+		"time":   bson.M{"$gte": snapshot.Time},
+	}).All(&records)
+	if err != nil {
+		return nil, karma.Format(err, "find removals")
+	}
+
+	actions := map[int64]string{}
+	for _, record := range records {
+		actions[record.UserID] = record.Action
+	}
+
+	for _, member := range previous {
+		if now[member] {
+			continue
+		}
+
+		switch actions[member] {
+		case auditKick:
+			churn.Kicked = append(churn.Kicked, member)
+		case auditRemove:
+			churn.Removed = append(churn.Removed, member)
+		default:
+			churn.Left = append(churn.Left, member)
+		}
+	}
+
+	return churn, nil
+}
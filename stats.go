@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	telebot "gopkg.in/telebot.v3"
+)
+
+type Stats struct {
+	Time           int64   `bson:"time"`
+	Tracked        int     `bson:"tracked"`
+	Inside         int     `bson:"inside"`
+	Outside        int     `bson:"outside"`
+	MedianIdle     int64   `bson:"median_idle"`
+	P90Idle        int64   `bson:"p90_idle"`
+	MessagesPerDay float64 `bson:"messages_per_day"`
+}
+
+type statsTotals struct {
+	Tracked   int   `bson:"tracked"`
+	Messages  int64 `bson:"messages"`
+	Idle      int64 `bson:"idle"`
+	FirstSeen int64 `bson:"first_seen"`
+}
+
+func (watcher *Watcher) aggregateStats() (Stats, error) {
+	now := time.Now()
+	stats := Stats{Time: now.Unix()}
+
+	var totals statsTotals
+	err := watcher.store.Pipe([]bson.M{
+		{"$group": bson.M{
+			"_id":      nil,
+			"tracked":  bson.M{"$sum": 1},
+			"messages": bson.M{"$sum": "$messages"},
+			"idle": bson.M{"$sum": bson.M{
+				"$max": []interface{}{
+					0, bson.M{"$subtract": []interface{}{now.Unix(), "$last_message"}},
+				},
+			}},
+			"first_seen": bson.M{"$min": bson.M{
+				"$cond": []interface{}{
+					bson.M{"$gt": []interface{}{"$first_seen", 0}}, "$first_seen", nil,
+				},
+			}},
+		}},
+	}).One(&totals)
+	if err != nil && err != mgo.ErrNotFound {
+		return Stats{}, karma.Format(err, "aggregate users")
+	}
+
+	stats.Tracked = totals.Tracked
+
+	stats.Inside, err = watcher.store.Find(bson.M{
+		"last_message": bson.M{
+			"$gt": now.Add(-watcher.policy().Duration).Unix(),
+		},
+	}).Count()
+	if err != nil {
+		return Stats{}, karma.Format(err, "count active users")
+	}
+
+	stats.Outside = stats.Tracked - stats.Inside
+
+	buckets := map[float64]uint64{}
+	for _, bound := range idleBuckets {
+		count, err := watcher.store.Find(bson.M{
+			"last_message": bson.M{
+				"$gte": now.Unix() - int64(bound),
+			},
+		}).Count()
+		if err != nil {
+			return Stats{}, karma.Format(err, "count users idle for %vs", bound)
+		}
+
+		buckets[bound] = uint64(count)
+	}
+
+	metricIdle.Update(uint64(stats.Tracked), float64(totals.Idle), buckets)
+
+	stats.MedianIdle, err = watcher.percentileIdle(now, stats.Tracked, 0.5)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats.P90Idle, err = watcher.percentileIdle(now, stats.Tracked, 0.9)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	firstSeen := now.Unix()
+	if totals.FirstSeen != 0 && totals.FirstSeen < firstSeen {
+		firstSeen = totals.FirstSeen
+	}
+
+	days := now.Sub(time.Unix(firstSeen, 0)).Hours() / 24
+	if days >= 1 {
+		stats.MessagesPerDay = float64(totals.Messages) / days
+	} else {
+		stats.MessagesPerDay = float64(totals.Messages)
+	}
+
+	return stats, nil
+}
+
+func percentileIndex(total int, rank float64) int {
+	index := int(math.Ceil(rank*float64(total))) - 1
+	if index < 0 {
+		index = 0
+	}
+
+	return index
+}
+
+func (watcher *Watcher) percentileIdle(
+	now time.Time,
+	total int,
+	rank float64,
+) (int64, error) {
+	if total == 0 {
+		return 0, nil
+	}
+
+	var user User
+	err := watcher.store.Find(nil).
+		Select(bson.M{"last_message": 1}).
+		Sort("-last_message").
+		Skip(percentileIndex(total, rank)).
+		One(&user)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return 0, nil
+		}
+
+		return 0, karma.Format(err, "find idle percentile %v", rank)
+	}
+
+	idle := now.Unix() - user.LastMessage
+	if idle < 0 {
+		idle = 0
+	}
+
+	return idle, nil
+}
+
+func (watcher *Watcher) saveStats(stats Stats) error {
+	err := watcher.history.Insert(stats)
+	if err != nil {
+		return karma.Format(err, "save stats")
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) statsBefore(moment time.Time) (*Stats, error) {
+	var stats Stats
+	err := watcher.history.Find(bson.M{
+		"time": bson.M{"$lte": moment.Unix()},
+	}).Sort("-time").One(&stats)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, nil
+		}
+
+		return nil, karma.Format(err, "find stats before %v", moment)
+	}
+
+	return &stats, nil
+}
+
+func (watcher *Watcher) summary() (string, error) {
+	stats, err := watcher.aggregateStats()
+	if err != nil {
+		return "", err
+	}
+
+	previous, err := watcher.statsBefore(time.Now().Add(-week))
+	if err != nil {
+		return "", err
+	}
+
+	language := watcher.chatLanguage()
+
+	text := fmt.Sprintf(
+		"Tracked members: %d\n"+
+			"Inside threshold: %d\n"+
+			"Outside threshold: %d\n"+
+			"Median idle: %s\n"+
+			"P90 idle: %s\n"+
+			"Messages per day: %.1f",
+		stats.Tracked,
+		stats.Inside,
+		stats.Outside,
+		humanDuration(time.Duration(stats.MedianIdle)*time.Second, language),
+		humanDuration(time.Duration(stats.P90Idle)*time.Second, language),
+		stats.MessagesPerDay,
+	)
+
+	if previous != nil {
+		text += fmt.Sprintf(
+			"\n\nTrend vs last week:\n"+
+				"Tracked members: %+d\n"+
+				"Inside threshold: %+d\n"+
+				"Median idle: %s\n"+
+				"Messages per day: %+.1f",
+			stats.Tracked-previous.Tracked,
+			stats.Inside-previous.Inside,
+			signedDuration(
+				time.Duration(stats.MedianIdle-previous.MedianIdle)*time.Second,
+				language,
+			),
+			stats.MessagesPerDay-previous.MessagesPerDay,
+		)
+	}
+
+	return text, nil
+}
+
+func (watcher *Watcher) handleStats(context telebot.Context) error {
+	text, err := watcher.summary()
+	if err != nil {
+		return err
+	}
+
+	return context.Reply(text)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const (
+	topicNormal = "normal"
+	topicIgnore = "ignore"
+	topicDouble = "double"
+)
+
+func (watcher *Watcher) topicRule(message *telebot.Message) string {
+	if message == nil || !message.TopicMessage {
+		return topicNormal
+	}
+
+	rule, ok := watcher.getSettings().Topics[strconv.Itoa(message.ThreadID)]
+	if !ok {
+		return topicNormal
+	}
+
+	return rule
+}
+
+func (watcher *Watcher) handleTopic(context telebot.Context) error {
+	message := context.Message()
+
+	args := context.Args()
+	if len(args) != 1 ||
+		(args[0] != topicNormal && args[0] != topicIgnore && args[0] != topicDouble) {
+		return context.Reply("Usage: /topic <normal|ignore|double>, sent inside the topic")
+	}
+
+	if !message.TopicMessage {
+		return context.Reply("Send this command inside a forum topic.")
+	}
+
+	id := strconv.Itoa(message.ThreadID)
+
+	log.Infof(
+		nil,
+		"topic %v marked as %s by %v",
+		message.ThreadID, args[0], loggedUser(context.Sender().ID),
+	)
+
+	err := watcher.updateSettings(
+		context.Sender().ID,
+		func(settings *Settings) {
+			if args[0] == topicNormal {
+				delete(settings.Topics, id)
+			} else {
+				settings.Topics[id] = args[0]
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return context.Reply("Messages in this topic now count as " + args[0] + ".")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+func (watcher *Watcher) sendPrivate(
+	user *telebot.User,
+	what interface{},
+	options ...interface{},
+) (*telebot.Message, error) {
+	message, err := watcher.bot.Send(user, what, options...)
+	if err != nil {
+		if isForbidden(err) {
+			watcher.setUnreachable(user.ID, true)
+		}
+
+		return nil, err
+	}
+
+	watcher.setUnreachable(user.ID, false)
+
+	return message, nil
+}
+
+func (watcher *Watcher) notifyAdmins(what interface{}, options ...interface{}) error {
+	admins, err := watcher.bot.AdminsOf(watcher.getChat())
+	if err != nil {
+		return karma.Format(err, "get chat admins")
+	}
+
+	for _, admin := range admins {
+		if admin.User.IsBot {
+			continue
+		}
+
+		_, err := watcher.sendPrivate(admin.User, what, options...)
+		if err != nil {
+			log.Errorf(err, "send message to admin: %v", loggedUser(admin.User.ID))
+		}
+	}
+
+	return nil
+}
+
+func isForbidden(err error) bool {
+	var apiErr *telebot.Error
+	return karma.Find(err, &apiErr) && apiErr.Code == http.StatusForbidden
+}
+
+func (watcher *Watcher) setUnreachable(user int64, unreachable bool) {
+	_, err := watcher.store.UpdateAll(
+		bson.M{"user_id": user},
+		bson.M{"$set": bson.M{"unreachable": unreachable}},
+	)
+	if err != nil {
+		log.Errorf(err, "update unreachable status: %v", loggedUser(user))
+	}
+}
+
+func (watcher *Watcher) handleUnreachable(context telebot.Context) error {
+	var users []User
+	err := watcher.store.Find(bson.M{"unreachable": true}).All(&users)
+	if err != nil {
+		return karma.Format(err, "find unreachable users")
+	}
+
+	if len(users) == 0 {
+		return context.Reply("All tracked users are reachable.")
+	}
+
+	entries := []string{}
+	for _, user := range users {
+		chat, err := watcher.bot.ChatByID(user.UserID)
+		if err != nil {
+			log.Errorf(err, "chat by id: %v", loggedUser(user.UserID))
+			continue
+		}
+
+		entries = append(
+			entries,
+			"@"+chat.Username+" "+chat.FirstName+" "+chat.LastName,
+		)
+	}
+
+	return context.Reply(
+		"Users who never started a private chat with the bot:\n" +
+			strings.Join(entries, "\n"),
+	)
+}
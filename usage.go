@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	telebot "gopkg.in/telebot.v3"
+)
+
+var (
+	metricCommands = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "telekick_commands_total",
+		Help: "Number of bot command invocations.",
+	}, []string{"command", "chat"})
+
+	metricCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "telekick_command_duration_seconds",
+		Help: "Time spent handling bot commands.",
+	}, []string{"command"})
+)
+
+type commandUsage struct {
+	count int
+	total time.Duration
+	chats map[string]int
+}
+
+type Usage struct {
+	commands map[string]*commandUsage
+	mutex    sync.Mutex
+}
+
+func NewUsage() *Usage {
+	return &Usage{commands: map[string]*commandUsage{}}
+}
+
+func commandName(message *telebot.Message) string {
+	fields := strings.Fields(message.Text)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return strings.SplitN(fields[0], "@", 2)[0]
+}
+
+func usageChat(chat *telebot.Chat) string {
+	if chat.Type == telebot.ChatPrivate {
+		return "private"
+	}
+
+	return strconv.FormatInt(chat.ID, 10)
+}
+
+func (usage *Usage) add(command string, chat string, took time.Duration) {
+	usage.mutex.Lock()
+	defer usage.mutex.Unlock()
+
+	entry, ok := usage.commands[command]
+	if !ok {
+		entry = &commandUsage{chats: map[string]int{}}
+		usage.commands[command] = entry
+	}
+
+	entry.count++
+	entry.total += took
+	entry.chats[chat]++
+}
+
+func (usage *Usage) Middleware(next telebot.HandlerFunc) telebot.HandlerFunc {
+	return func(context telebot.Context) error {
+		if context.Message() == nil {
+			return next(context)
+		}
+
+		command := commandName(context.Message())
+		chat := usageChat(context.Chat())
+
+		started := time.Now()
+		err := next(context)
+		took := time.Since(started)
+
+		metricCommands.WithLabelValues(command, chat).Inc()
+		metricCommandDuration.WithLabelValues(command).Observe(took.Seconds())
+
+		usage.add(command, chat, took)
+
+		return err
+	}
+}
+
+func (usage *Usage) String() string {
+	usage.mutex.Lock()
+	defer usage.mutex.Unlock()
+
+	names := []string{}
+	for name := range usage.commands {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return usage.commands[names[i]].count > usage.commands[names[j]].count
+	})
+
+	lines := []string{}
+	for _, name := range names {
+		entry := usage.commands[name]
+
+		chats := []string{}
+		for chat, count := range entry.chats {
+			chats = append(chats, fmt.Sprintf("%s: %d", chat, count))
+		}
+
+		sort.Strings(chats)
+
+		lines = append(lines, fmt.Sprintf(
+			"%s %d calls, avg %v (%s)",
+			name,
+			entry.count,
+			(entry.total/time.Duration(entry.count)).Round(time.Millisecond),
+			strings.Join(chats, ", "),
+		))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (watcher *Watcher) handleUsage(context telebot.Context) error {
+	text := watcher.usage.String()
+	if text == "" {
+		return context.Reply("No commands were used since start.")
+	}
+
+	return context.Reply(
+		"Command usage since " + watcher.started.Format(time.RFC1123) + ":\n" + text,
+	)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const vouchPeriod = 90 * day
+
+var (
+	vouchMarkup = &telebot.ReplyMarkup{}
+	btnVouch    = vouchMarkup.Data("Vouch", "vouch")
+)
+
+func (watcher *Watcher) warningMarkup(user int64) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	if watcher.vouchThreshold <= 0 {
+		return markup
+	}
+
+	markup.Inline(markup.Row(markup.Data(
+		fmt.Sprintf("%s (%d needed)", btnVouch.Text, watcher.vouchThreshold),
+		btnVouch.Unique,
+		strconv.FormatInt(user, 10),
+	)))
+
+	return markup
+}
+
+func (watcher *Watcher) handleVouch(context telebot.Context) error {
+	respond := func(text string) error {
+		return context.Respond(&telebot.CallbackResponse{Text: text})
+	}
+
+	target, err := strconv.ParseInt(context.Data(), 10, 64)
+	if err != nil {
+		return karma.Format(err, "parse user id: %s", context.Data())
+	}
+
+	voter := context.Sender().ID
+	if voter == target {
+		return respond("You can't vouch for yourself.")
+	}
+
+	var user User
+	err = watcher.store.Find(bson.M{"user_id": target}).One(&user)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return respond("This member is no longer tracked.")
+		}
+
+		return karma.Format(err, "find user: %v", loggedUser(target))
+	}
+
+	now := time.Now()
+	if user.WarnedAt == 0 ||
+		now.Sub(time.Unix(user.WarnedAt, 0)) > watcher.vouchWindow {
+		return respond("Vouching for this warning is closed.")
+	}
+
+	if now.Sub(time.Unix(user.VouchedAt, 0)) < vouchPeriod {
+		return respond("This member was already vouched for this quarter.")
+	}
+
+	err = watcher.store.Update(
+		bson.M{"user_id": target},
+		bson.M{"$addToSet": bson.M{"vouchers": voter}},
+	)
+	if err != nil {
+		return karma.Format(err, "add voucher: %v", loggedUser(target))
+	}
+
+	vouchers := map[int64]bool{voter: true}
+	for _, id := range user.Vouchers {
+		vouchers[id] = true
+	}
+
+	if len(vouchers) < watcher.vouchThreshold {
+		return respond(fmt.Sprintf(
+			"Thanks! %d of %d vouches collected.",
+			len(vouchers), watcher.vouchThreshold,
+		))
+	}
+
+	log.Infof(nil, "user %v vouched by %d members", loggedUser(target), len(vouchers))
+
+	err = watcher.store.Update(
+		bson.M{"user_id": target},
+		bson.M{
+			"$set": bson.M{
+				"last_message": now.Unix(),
+				"warned_at":    0,
+				"vouched_at":   now.Unix(),
+			},
+			"$unset": bson.M{"vouchers": ""},
+		},
+	)
+	if err != nil {
+		return karma.Format(err, "extend vouched user: %v", loggedUser(target))
+	}
+
+	err = watcher.record(AuditRecord{
+		UserID:   target,
+		Username: user.Username,
+		Action:   auditVouch,
+		Actor:    voter,
+		Details:  fmt.Sprintf("%d vouches", len(vouchers)),
+	})
+	if err != nil {
+		log.Error(err)
+	}
+
+	err = context.Edit(
+		context.Message().Text +
+			"\n\nVouched for by the community, the deadline is extended.",
+	)
+	if err != nil {
+		log.Errorf(err, "edit vouched warning")
+	}
+
+	return respond("Thanks! The deadline is extended.")
+}
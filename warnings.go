@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/reconquest/karma-go"
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+var warningTexts = map[string]string{
+	languageEnglish: "You have not written anything in the chat for %s. " +
+		"You will be %s after %s unless you post something.",
+	languageRussian: "Вы ничего не писали в чате уже %s. " +
+		"Если ничего не напишете, после %[3]s вы будете %[2]s.",
+}
+
+var russianActionVerbs = map[string]string{
+	actionKick: "удалены",
+	actionMute: "лишены права писать",
+}
+
+func (watcher *Watcher) warn(
+	context *karma.Context,
+	cycle string,
+	policy Policy,
+) error {
+	if policy.WarnBefore <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	var users []User
+	err := watcher.store.Find(watcher.rolloutFilter(bson.M{
+		"last_message": bson.M{
+			"$lt":  now.Add(policy.WarnBefore - policy.Duration).Unix(),
+			"$gte": now.Add(-policy.Duration).Unix(),
+		},
+		"warned_at": bson.M{"$in": []interface{}{0, nil}},
+	})).Sort("last_message", "user_id").All(&users)
+	if err != nil {
+		return context.Format(err, "find users to warn")
+	}
+
+	for _, user := range users {
+		exempt, provider, err := watcher.exempt(user)
+		if err != nil {
+			log.Errorf(context.Reason(err), "check exemption of %v, skip", loggedUser(user.UserID))
+			continue
+		}
+
+		if exempt {
+			log.Infof(context, "%v is exempt by %s, skip warning", loggedUser(user.UserID), provider)
+			continue
+		}
+
+		deadline := time.Unix(user.LastMessage, 0).Add(policy.Duration)
+
+		log.Infof(context, "warn %v, deadline: %v", loggedUser(user.UserID), deadline)
+
+		language := watcher.userLanguage(user.Language)
+
+		verb := actionVerb(policy.Action)
+		if language == languageRussian {
+			verb = russianActionVerbs[policy.Action]
+		}
+
+		text := fmt.Sprintf(
+			warningTexts[language],
+			humanDuration(now.Sub(time.Unix(user.LastMessage, 0)), language),
+			verb,
+			deadline.Format(time.RFC1123),
+		)
+
+		err = watcher.store.Update(
+			bson.M{"user_id": user.UserID},
+			bson.M{"$set": bson.M{"warned_at": now.Unix()}},
+		)
+		if err != nil {
+			return context.Format(err, "mark user as warned: %v", loggedUser(user.UserID))
+		}
+
+		_, err = watcher.sendPrivate(&telebot.User{ID: user.UserID}, text)
+		if err != nil {
+			log.Errorf(context.Reason(err), "send warning to %v", loggedUser(user.UserID))
+
+			if user.Username != "" {
+				_, err = watcher.bot.Send(
+					watcher.getChat(),
+					"@"+user.Username+" "+text,
+					watcher.warningMarkup(user.UserID),
+				)
+				if err != nil {
+					log.Errorf(context.Reason(err), "send warning to chat")
+				}
+			}
+		}
+
+		if err != nil {
+			err = watcher.store.Update(
+				bson.M{"user_id": user.UserID},
+				bson.M{"$set": bson.M{"warned_at": 0}},
+			)
+			if err != nil {
+				log.Error(context.Reason(err))
+			}
+
+			continue
+		}
+
+		err = watcher.record(AuditRecord{
+			UserID:   user.UserID,
+			Username: user.Username,
+			Action:   auditWarn,
+			Cycle:    cycle,
+		})
+		if err != nil {
+			log.Error(err)
+		}
+
+		metricWarnings.Inc()
+	}
+
+	return nil
+}
+
+func (watcher *Watcher) clearWarning(user *telebot.User) error {
+	err := watcher.store.Update(
+		bson.M{"user_id": user.ID, "warned_at": bson.M{"$gt": 0}},
+		bson.M{
+			"$set":   bson.M{"warned_at": 0},
+			"$unset": bson.M{"vouchers": ""},
+		},
+	)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil
+		}
+
+		return karma.Format(err, "clear warning: %v", loggedUser(user.ID))
+	}
+
+	log.Infof(nil, "warned user %v became active again", loggedUser(user.ID))
+
+	metricWarningsSaved.Inc()
+
+	return watcher.record(AuditRecord{
+		UserID:   user.ID,
+		Username: user.Username,
+		Action:   auditSaved,
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/reconquest/pkg/log"
+	telebot "gopkg.in/telebot.v3"
+)
+
+const (
+	pollerBackoffMin = time.Second
+	pollerBackoffMax = 5 * time.Minute
+	pollerPingFails  = 3
+)
+
+type WatchdogPoller struct {
+	lastUpdate int64
+
+	poller  telebot.Poller
+	silence time.Duration
+	restart chan struct{}
+	drain   chan chan struct{}
+}
+
+func NewWatchdogPoller(poller telebot.Poller, silence time.Duration) *WatchdogPoller {
+	return &WatchdogPoller{
+		poller:  poller,
+		silence: silence,
+		restart: make(chan struct{}),
+		drain:   make(chan chan struct{}),
+	}
+}
+
+func (watchdog *WatchdogPoller) touch() {
+	atomic.StoreInt64(&watchdog.lastUpdate, time.Now().Unix())
+}
+
+func (watchdog *WatchdogPoller) Poll(
+	bot *telebot.Bot,
+	dest chan telebot.Update,
+	stop chan struct{},
+) {
+	updates := make(chan telebot.Update)
+	relayed := make(chan struct{})
+
+	go func() {
+		for update := range updates {
+			watchdog.touch()
+
+			select {
+			case dest <- update:
+			case <-stop:
+			}
+		}
+
+		close(relayed)
+	}()
+
+	drained := func(confirm chan struct{}) {
+		close(updates)
+		<-relayed
+		close(confirm)
+		<-stop
+	}
+
+	go watchdog.watch(bot)
+
+	backoff := pollerBackoffMin
+
+	for {
+		pollerStop := make(chan struct{})
+		done := make(chan struct{})
+
+		started := time.Now()
+		watchdog.touch()
+
+		go func() {
+			watchdog.poller.Poll(bot, updates, pollerStop)
+			close(done)
+		}()
+
+		select {
+		case <-stop:
+			close(pollerStop)
+			<-done
+			close(updates)
+			return
+		case confirm := <-watchdog.drain:
+			close(pollerStop)
+			<-done
+			drained(confirm)
+			return
+		case <-done:
+			log.Warningf(nil, "poller exited unexpectedly")
+		case <-watchdog.restart:
+			log.Warningf(nil, "poller is silent, restarting")
+			close(pollerStop)
+			<-done
+		}
+
+		if time.Since(started) > pollerBackoffMax {
+			backoff = pollerBackoffMin
+		}
+
+		log.Infof(nil, "restart poller in %v", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-stop:
+			close(updates)
+			return
+		case confirm := <-watchdog.drain:
+			drained(confirm)
+			return
+		}
+
+		backoff *= 2
+		if backoff > pollerBackoffMax {
+			backoff = pollerBackoffMax
+		}
+	}
+}
+
+func (watchdog *WatchdogPoller) Drain() {
+	confirm := make(chan struct{})
+	watchdog.drain <- confirm
+	<-confirm
+}
+
+func (watchdog *WatchdogPoller) watch(bot *telebot.Bot) {
+	fails := 0
+
+	for range time.Tick(watchdog.silence / 2) {
+		last := time.Unix(atomic.LoadInt64(&watchdog.lastUpdate), 0)
+		if time.Since(last) < watchdog.silence {
+			fails = 0
+			continue
+		}
+
+		_, err := bot.Raw("getMe", nil)
+		if err == nil {
+			fails = 0
+			continue
+		}
+
+		fails++
+		log.Errorf(err, "poller silent for %v, getMe failed %d times",
+			time.Since(last), fails)
+
+		if fails < pollerPingFails {
+			continue
+		}
+
+		fails = 0
+
+		select {
+		case watchdog.restart <- struct{}{}:
+		default:
+		}
+	}
+}